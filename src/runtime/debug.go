@@ -180,6 +180,243 @@ func NumGoroutine() int {
 	return int(gcount(false))
 }
 
+// ContextSwitches returns the number of times a goroutine has been
+// descheduled, either voluntarily (it blocked or yielded) or
+// involuntarily (it was preempted).
+func ContextSwitches() uint64 {
+	nvcsw, nivcsw := readContextSwitches()
+	return nvcsw + nivcsw
+}
+
+// readContextSwitches returns the total voluntary and involuntary
+// goroutine context switches.
+func readContextSwitches() (nvcsw, nivcsw uint64) {
+	// Lock the scheduler so the number of Ps can't change and
+	// destroyed Ps have been folded into sched.
+	lock(&sched.lock)
+	nvcsw, nivcsw = sched.nvcsw.Load(), sched.nivcsw.Load()
+	for _, pp := range allp {
+		nvcsw += pp.nvcsw
+		nivcsw += pp.nivcsw
+	}
+	unlock(&sched.lock)
+	return
+}
+
+// GoroutineContextSwitches returns the number of times the goroutine
+// with the given ID has been descheduled voluntarily and involuntarily,
+// as counted by [ContextSwitches]. The result ok is false if there is
+// no such goroutine.
+//
+// Per-goroutine counts are only kept by runtimes built with the
+// schedstats build tag, as with "go build -tags schedstats". Otherwise
+// ok is always false.
+func GoroutineContextSwitches(id uint64) (voluntary, involuntary uint64, ok bool) {
+	if !schedStatsEnabled {
+		return
+	}
+	forEachG(func(gp *g) {
+		if !ok && gp.goid == id && readgstatus(gp) != _Gdead {
+			voluntary, involuntary = gp.schedStats.contextSwitches()
+			ok = true
+		}
+	})
+	return
+}
+
+// ForcedPreemptions returns the number of times a goroutine has been
+// preempted asynchronously, by interrupting the thread running it,
+// because it did not reach a cooperative preemption point in time.
+// Goroutines that are preempted often are usually running long loops
+// without function calls.
+func ForcedPreemptions() uint64 {
+	lock(&sched.lock)
+	n := sched.asyncPreempts.Load()
+	for _, pp := range allp {
+		n += pp.asyncPreempts
+	}
+	unlock(&sched.lock)
+	return n
+}
+
+// GoroutineForcedPreemptions returns the number of times the goroutine
+// with the given ID has been preempted asynchronously, as counted by
+// [ForcedPreemptions]. The result ok is false if there is no such
+// goroutine.
+//
+// Per-goroutine counts are only kept by runtimes built with the
+// schedstats build tag. Otherwise ok is always false.
+func GoroutineForcedPreemptions(id uint64) (n uint64, ok bool) {
+	if !schedStatsEnabled {
+		return
+	}
+	forEachG(func(gp *g) {
+		if !ok && gp.goid == id && readgstatus(gp) != _Gdead {
+			n, ok = gp.schedStats.forcedPreemptions(), true
+		}
+	})
+	return
+}
+
+// RunnextGoroutines returns the ID of the goroutine in each P's runnext
+// slot, keyed by P ID, or 0 if the slot is empty. A goroutine in the
+// runnext slot runs before any other goroutine queued on that P, and
+// typically was just readied by the goroutine currently running there.
+//
+// The result is a snapshot; the slots may change as soon as it is taken.
+func RunnextGoroutines() map[int]uint64 {
+	for {
+		// Allocate before taking sched.lock, and retry if
+		// the number of Ps changed in the meantime.
+		n := int(gomaxprocs)
+		goids := make([]uint64, n)
+		lock(&sched.lock)
+		if len(allp) != n {
+			unlock(&sched.lock)
+			continue
+		}
+		for i, pp := range allp {
+			if gp := guintptr(atomic.Loaduintptr((*uintptr)(&pp.runnext))).ptr(); gp != nil {
+				goids[i] = gp.goid
+			}
+		}
+		unlock(&sched.lock)
+
+		m := make(map[int]uint64, n)
+		for i, goid := range goids {
+			m[i] = goid
+		}
+		return m
+	}
+}
+
+// GoroutineRunHistory returns the IDs of the Ps that the goroutine with
+// the given ID most recently ran on, oldest first. Consecutive runs on
+// the same P appear once, and only the last 8 Ps are kept. It returns
+// nil if there is no such goroutine.
+//
+// The history is read without stopping the goroutine. If it is being
+// scheduled onto a new P at the same moment, the result may or may not
+// include that P.
+//
+// Run histories are only kept by runtimes built with the schedstats
+// build tag. Otherwise GoroutineRunHistory always returns nil.
+func GoroutineRunHistory(id uint64) []int {
+	if !schedStatsEnabled {
+		return nil
+	}
+	var h pHistory
+	found := false
+	forEachG(func(gp *g) {
+		if !found && gp.goid == id && readgstatus(gp) != _Gdead {
+			h, found = gp.schedStats.runs(), true
+		}
+	})
+	if !found {
+		return nil
+	}
+	return h.list()
+}
+
+// AsyncPreemptEnabled reports whether the runtime can preempt goroutines
+// asynchronously, by interrupting the thread running them. This is
+// disabled on some platforms and by GODEBUG=asyncpreemptoff=1.
+//
+// Without asynchronous preemption, a goroutine can only be preempted at
+// function calls and other cooperative preemption points, so a loop
+// without calls may run for an unbounded time. Scheduling latency and
+// fairness guarantees that depend on time slices do not hold then.
+func AsyncPreemptEnabled() bool {
+	return preemptMSupported && debug.asyncpreemptoff == 0
+}
+
+// A Topology describes how the scheduler's Ps are laid out.
+type Topology struct {
+	// NumP is the number of Ps, which is the current GOMAXPROCS setting.
+	NumP int
+
+	// M holds the ID of the M (operating system thread) currently
+	// bound to each P, indexed by P ID, or -1 if the P is idle.
+	M []int64
+}
+
+// SchedulerTopology returns the current layout of the scheduler's Ps.
+//
+// Ps are not bound to CPUs or NUMA nodes: the operating system may run
+// the thread holding a P on any CPU, and move it at any time. The M
+// bindings are a snapshot and may change as soon as they are read.
+func SchedulerTopology() Topology {
+	for {
+		// Allocate before taking sched.lock, and retry if
+		// the number of Ps changed in the meantime.
+		n := int(gomaxprocs)
+		t := Topology{
+			NumP: n,
+			M:    make([]int64, n),
+		}
+		lock(&sched.lock)
+		if len(allp) != n {
+			unlock(&sched.lock)
+			continue
+		}
+		for i, pp := range allp {
+			t.M[i] = -1
+			if mp := pp.m.ptr(); mp != nil {
+				t.M[i] = mp.id
+			}
+		}
+		unlock(&sched.lock)
+		return t
+	}
+}
+
+// SchedulerMetrics holds cumulative counts of scheduling events since
+// the program started. See [ReadSchedulerMetrics].
+type SchedulerMetrics struct {
+	// GlobalQueueChecks is the number of times a P locked the global
+	// run queue to take goroutines from it.
+	GlobalQueueChecks uint64
+
+	// LocalDequeues is the number of goroutines a P took from its own
+	// local run queue to run.
+	LocalDequeues uint64
+
+	// GlobalDequeues is the number of goroutines taken from the global
+	// run queue, including those moved to a local run queue in a batch.
+	GlobalDequeues uint64
+
+	// Steals is the number of times a P stole goroutines from another
+	// P's local run queue.
+	Steals uint64
+
+	// Parks is the number of times a goroutine blocked, for example on
+	// a channel operation, a mutex, or I/O.
+	Parks uint64
+}
+
+// ReadSchedulerMetrics populates m with the scheduler's event counts.
+//
+// The counts are cumulative and never decrease, so the difference
+// between two readings describes the scheduling activity between them.
+func ReadSchedulerMetrics(m *SchedulerMetrics) {
+	// Lock the scheduler so the number of Ps can't change and
+	// destroyed Ps have been folded into sched.
+	lock(&sched.lock)
+	c := sched.schedCounts
+	for _, pp := range allp {
+		c.add(&pp.schedCounts)
+	}
+	unlock(&sched.lock)
+
+	*m = SchedulerMetrics{
+		GlobalQueueChecks: c.globalChecks,
+		LocalDequeues:     c.localDequeues,
+		GlobalDequeues:    c.globalDequeues,
+		Steals:            c.steals,
+		Parks:             c.parks,
+	}
+}
+
 //go:linkname debug_modinfo runtime/debug.modinfo
 func debug_modinfo() string {
 	return modinfo
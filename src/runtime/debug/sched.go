@@ -0,0 +1,58 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"time"
+)
+
+// WaitForSchedulerDrain blocks until no goroutines other than the caller
+// are running or runnable, or until timeout elapses. It reports whether
+// the scheduler drained before the timeout.
+//
+// Goroutines created by the runtime, such as those running finalizers
+// or the garbage collector, are not counted. Neither are goroutines
+// blocked on channels, locks, timers or I/O, so a server can use
+// WaitForSchedulerDrain after it stops accepting work to let in-flight
+// work finish before it exits. Goroutines in system calls or cgo calls
+// are not counted either, even if the call is about to return.
+func WaitForSchedulerDrain(timeout time.Duration) bool {
+	const maxSleep = 10 * time.Millisecond
+	deadline := time.Now().Add(timeout)
+	sleep := 50 * time.Microsecond
+	for runnableGoroutines() > 0 {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false
+		}
+		time.Sleep(min(sleep, remaining))
+		sleep = min(2*sleep, maxSleep)
+	}
+	return true
+}
+
+// StealHeatmap returns the number of times each P stole goroutines from
+// each other P's run queue during the next window. It blocks for the
+// duration of the window. The result is indexed by the ID of the P that
+// stole, then by the ID of the P it stole from, so a column with large
+// counts identifies a P whose work is being spread to the others.
+//
+// If GOMAXPROCS changes during the window, the result covers only the
+// time since the last change.
+func StealHeatmap(window time.Duration) [][]uint64 {
+	before, gen := readStealMatrix()
+	time.Sleep(window)
+	after, afterGen := readStealMatrix()
+	if afterGen != gen {
+		// GOMAXPROCS changed and the counts started over.
+		return after
+	}
+	for i, row := range after {
+		for j := range row {
+			row[j] -= before[i][j]
+		}
+	}
+	return after
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForSchedulerDrain(t *testing.T) {
+	const n = 8
+	var finished atomic.Int32
+	for i := 0; i < n; i++ {
+		go func() {
+			end := time.Now().Add(20 * time.Millisecond)
+			for time.Now().Before(end) {
+			}
+			finished.Add(1)
+		}()
+	}
+	if !WaitForSchedulerDrain(10 * time.Second) {
+		t.Fatalf("WaitForSchedulerDrain timed out with %d of %d goroutines finished", finished.Load(), n)
+	}
+	if got := finished.Load(); got != n {
+		t.Errorf("WaitForSchedulerDrain returned with %d of %d goroutines finished", got, n)
+	}
+}
+
+func TestWaitForSchedulerDrainTimeout(t *testing.T) {
+	var stop atomic.Bool
+	done := make(chan bool)
+	go func() {
+		for !stop.Load() {
+		}
+		close(done)
+	}()
+	defer func() {
+		stop.Store(true)
+		<-done
+	}()
+	if WaitForSchedulerDrain(20 * time.Millisecond) {
+		t.Errorf("WaitForSchedulerDrain reported drained while a goroutine was running")
+	}
+}
+
+func TestWaitForSchedulerDrainIgnoresBlocked(t *testing.T) {
+	c := make(chan int)
+	defer close(c)
+	go func() {
+		<-c
+	}()
+	if !WaitForSchedulerDrain(10 * time.Second) {
+		t.Errorf("WaitForSchedulerDrain counted a blocked goroutine as runnable")
+	}
+}
+
+func TestStealHeatmap(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	m := StealHeatmap(10 * time.Millisecond)
+	if len(m) != procs {
+		t.Fatalf("StealHeatmap returned %d rows, want GOMAXPROCS %d", len(m), procs)
+	}
+	for i, row := range m {
+		if len(row) != procs {
+			t.Errorf("row %d has %d columns, want %d", i, len(row), procs)
+		} else if row[i] != 0 {
+			t.Errorf("P %d stole from itself %d times", i, row[i])
+		}
+	}
+}
+
+// spawnWork creates short-lived goroutines from a single goroutine, so
+// that they queue up on one P and the other Ps steal them, until stop
+// is closed.
+func spawnWork(stop <-chan bool) {
+	var wg sync.WaitGroup
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				end := time.Now().Add(20 * time.Microsecond)
+				for time.Now().Before(end) {
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// startSpawnWork runs spawnWork in a new goroutine, and returns a
+// function that stops it and waits for it to return.
+func startSpawnWork() (stop func()) {
+	c := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		spawnWork(c)
+		close(done)
+	}()
+	return func() {
+		close(c)
+		<-done
+	}
+}
+
+func totalSteals(m [][]uint64) (n uint64) {
+	for _, row := range m {
+		for _, v := range row {
+			n += v
+		}
+	}
+	return n
+}
+
+func TestStealHeatmapProducer(t *testing.T) {
+	const procs = 4
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+	stop := startSpawnWork()
+	m := StealHeatmap(50 * time.Millisecond)
+	stop()
+
+	if len(m) != procs {
+		t.Fatalf("StealHeatmap returned %d rows, want %d", len(m), procs)
+	}
+	if totalSteals(m) == 0 {
+		t.Errorf("no steals while one goroutine created work for %d Ps", procs)
+	}
+	for i, row := range m {
+		if row[i] != 0 {
+			t.Errorf("P %d stole from itself %d times", i, row[i])
+		}
+	}
+}
+
+func TestStealHeatmapGOMAXPROCSChange(t *testing.T) {
+	const procs = 4
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+	stop := startSpawnWork()
+	defer stop()
+
+	// Build up steal counts before the window, so that counts that
+	// start over during it are likely smaller than those it started
+	// from.
+	time.Sleep(200 * time.Millisecond)
+	c := make(chan [][]uint64)
+	go func() {
+		c <- StealHeatmap(60 * time.Millisecond)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	runtime.GOMAXPROCS(procs / 2)
+	runtime.GOMAXPROCS(procs)
+	m := <-c
+
+	if len(m) != procs {
+		t.Fatalf("StealHeatmap returned %d rows, want %d", len(m), procs)
+	}
+	if totalSteals(m) == 0 {
+		t.Errorf("StealHeatmap lost the steals made after GOMAXPROCS changed")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package debug_test
+
+import (
+	. "runtime/debug"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWaitForSchedulerDrainIgnoresSyscall(t *testing.T) {
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(p[1])
+	defer syscall.Close(p[0])
+
+	// A read from an empty blocking pipe stays in the kernel until
+	// something is written.
+	done := make(chan bool)
+	go func() {
+		var buf [1]byte
+		syscall.Read(p[0], buf[:])
+		close(done)
+	}()
+	defer func() {
+		syscall.Write(p[1], []byte{0})
+		<-done
+	}()
+	if !WaitForSchedulerDrain(10 * time.Second) {
+		t.Errorf("WaitForSchedulerDrain counted a goroutine blocked in a system call as runnable")
+	}
+}
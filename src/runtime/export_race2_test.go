@@ -0,0 +1,74 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime
+
+import "unsafe"
+
+const Race2ShadowChunkBytes = heapArenaBytes
+
+func Race2MapShadow(addr, size uintptr) {
+	race2mapshadow(unsafe.Pointer(addr), size)
+}
+
+// Race2Shadow returns the shadow of the application memory [addr, addr+size).
+// The shadow must be mapped.
+func Race2Shadow(addr, size uintptr) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(race2shadowAddr(addr))), size>>race2ShadowScale)
+}
+
+// A Race2Epoch identifies a point in a goroutine's execution for the
+// race2 detector's happens-before tracking.
+type Race2Epoch struct {
+	slot  uint32
+	epoch uint64
+}
+
+// Race2Now returns the current epoch of the calling goroutine.
+func Race2Now() Race2Epoch {
+	ctx := race2ctxOf(getg().racectx)
+	return Race2Epoch{ctx.slot, ctx.clock[ctx.slot]}
+}
+
+// Race2Sees reports whether e happens before the current point of the
+// calling goroutine.
+func Race2Sees(e Race2Epoch) bool {
+	ctx := race2ctxOf(getg().racectx)
+	return ctx.clock[e.slot] >= e.epoch
+}
+
+func (e Race2Epoch) SameSlot(o Race2Epoch) bool {
+	return e.slot == o.slot
+}
+
+var (
+	Race2Acquire      = race2acquire
+	Race2Release      = race2release
+	Race2ReleaseMerge = race2releasemerge
+)
+
+// Race2CaptureReports runs f, forgetting which races were already
+// reported, and returns the races found and the reports printed while
+// it ran. The races are not counted by internal/race.Errors, so they
+// do not fail the test.
+func Race2CaptureReports(f func()) (races int, reports string) {
+	buf := new([]byte)
+	*buf = make([]byte, 0, 64<<10)
+	lock(&race2accesses.lock)
+	race2accesses.nreported = 0
+	start := race2accesses.reports
+	unlock(&race2accesses.lock)
+
+	race2reportBuf = buf
+	f()
+	race2reportBuf = nil
+
+	lock(&race2accesses.lock)
+	races = race2accesses.reports - start
+	race2accesses.reports = start
+	unlock(&race2accesses.lock)
+	return races, string(*buf)
+}
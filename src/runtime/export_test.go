@@ -122,6 +122,30 @@ func RunSchedLocalQueueTest() {
 	}
 }
 
+// RunRunnextStreakTest checks that a P starts a new runnext streak when
+// it takes the runnext G it passed over because its local run queue was
+// empty. The runnext streak limit must be 1.
+func RunRunnextStreakTest() {
+	pp := new(p)
+	gs := make([]g, 4)
+	Escape(gs) // Ensure gs doesn't move, since we use guintptrs
+	runqput(pp, &gs[0], true)
+	if gp, _ := runqget(pp); gp != &gs[0] {
+		throw("runnext not taken")
+	}
+	// The streak is at the limit, but there is nothing else to run.
+	runqput(pp, &gs[1], true)
+	if gp, _ := runqget(pp); gp != &gs[1] {
+		throw("passed-over runnext not taken from an empty runq")
+	}
+	// That started a new streak, so runnext goes first again.
+	runqput(pp, &gs[2], false)
+	runqput(pp, &gs[3], true)
+	if gp, _ := runqget(pp); gp != &gs[3] {
+		throw("runnext passed over at the start of a streak")
+	}
+}
+
 func RunSchedLocalQueueStealTest() {
 	p1 := new(p)
 	p2 := new(p)
@@ -240,6 +264,36 @@ const PtrSize = goarch.PtrSize
 
 var ForceGCPeriod = &forcegcperiod
 
+// SetRunnextStreakLimitForTest sets the number of consecutive times
+// a P runs its runnext goroutine before it runs one from its local run
+// queue, and returns the previous limit. A limit of 0 means no limit.
+// The world is stopped so that no P is in runqget, and each P starts
+// a new streak under the new limit.
+func SetRunnextStreakLimitForTest(n int) int {
+	stw := stopTheWorld(stwForTestRunnextStreakLimit)
+	old := runnextStreakLimit
+	runnextStreakLimit = uint32(n)
+	for _, pp := range allp {
+		pp.runnextStreak = 0
+	}
+	startTheWorld(stw)
+	return int(old)
+}
+
+// SetGlobalQueueIntervalForTest sets the number of scheduler ticks
+// between the checks a P makes of the global run queue before its local
+// run queue, and returns the previous interval. An interval of 0 selects
+// the default.
+func SetGlobalQueueIntervalForTest(n int32) int32 {
+	if n < 0 {
+		panic("negative global run queue interval")
+	}
+	if n == 0 {
+		n = 61
+	}
+	return int32(atomic.Xchg(&globalRunqInterval, uint32(n)))
+}
+
 // SetTracebackEnv is like runtime/debug.SetTraceback, but it raises
 // the "environment" traceback level, so later calls to
 // debug.SetTraceback (e.g., from testing timeouts) can't lower it.
@@ -494,6 +548,114 @@ func LockOSCounts() (external, internal uint32) {
 	return gp.m.lockedExt, gp.m.lockedInt
 }
 
+func ProcPin() int {
+	return procPin()
+}
+
+func ProcUnpin() {
+	procUnpin()
+}
+
+func StealMatrixForTest() [][]uint64 {
+	m, _ := readStealMatrix()
+	return m
+}
+
+func ContextSwitchesForTest() (voluntary, involuntary uint64) {
+	return readContextSwitches()
+}
+
+type StealStats struct {
+	Calls     uint64 // calls to stealWork
+	Successes uint64 // calls that stole a goroutine
+	Failures  uint64 // calls that found nothing to steal
+}
+
+func StealStatsForTest() StealStats {
+	lock(&sched.lock)
+	s := StealStats{
+		Calls:     sched.stealCalls.Load(),
+		Successes: sched.stealHits.Load(),
+		Failures:  sched.stealMisses.Load(),
+	}
+	for _, pp := range allp {
+		s.Calls += pp.stealCalls
+		s.Successes += pp.stealHits
+		s.Failures += pp.stealMisses
+	}
+	unlock(&sched.lock)
+	return s
+}
+
+// ResetStealStatsForTest zeroes the steal counters. The world is
+// stopped so that no P is counting a steal while its count is reset.
+func ResetStealStatsForTest() {
+	stw := stopTheWorld(stwForTestResetStealStats)
+	sched.stealCalls.Store(0)
+	sched.stealHits.Store(0)
+	sched.stealMisses.Store(0)
+	for _, pp := range allp {
+		pp.stealCalls = 0
+		pp.stealHits = 0
+		pp.stealMisses = 0
+	}
+	startTheWorld(stw)
+}
+
+type SchedDecision struct {
+	Source string // "local", "global", "steal" or "idle"
+	Goid   uint64 // 0 for "idle"
+	P      int
+	When   int64
+}
+
+// RandomizeScheduler reports whether runqput randomly puts new
+// goroutines at the tail of the run queue instead of in runnext.
+const RandomizeScheduler = randomizeScheduler
+
+type RunnableInfo struct {
+	Goid      uint64
+	PID       int // -1 for the global run queue
+	IsRunnext bool
+}
+
+// RunnableSnapshot returns the goroutines in the run queues, with the
+// world stopped so that the queues are consistent with each other.
+// Each P's runnext comes before its local run queue, which is in
+// dequeue order, as is the global run queue at the end.
+func RunnableSnapshot() []RunnableInfo {
+	stw := stopTheWorld(stwForTestRunnableSnapshot)
+
+	var infos []RunnableInfo
+	for _, pp := range allp {
+		if gp := pp.runnext.ptr(); gp != nil {
+			infos = append(infos, RunnableInfo{gp.goid, int(pp.id), true})
+		}
+		for i := pp.runqhead; i != pp.runqtail; i++ {
+			gp := pp.runq[i%uint32(len(pp.runq))].ptr()
+			infos = append(infos, RunnableInfo{gp.goid, int(pp.id), false})
+		}
+	}
+	for gp := sched.runq.head.ptr(); gp != nil; gp = gp.schedlink.ptr() {
+		infos = append(infos, RunnableInfo{gp.goid, -1, false})
+	}
+
+	startTheWorld(stw)
+	return infos
+}
+
+func EnableDecisionLogForTest(enable bool) {
+	setSchedDecisionLog(enable)
+}
+
+func DecisionLogForTest() []SchedDecision {
+	var log []SchedDecision
+	for _, d := range readSchedDecisionLog() {
+		log = append(log, SchedDecision{d.source.String(), d.goid, int(d.pid), d.when})
+	}
+	return log
+}
+
 //go:noinline
 func TracebackSystemstack(stk []uintptr, i int) int {
 	if i == 0 {
@@ -555,6 +717,10 @@ type Sudog = sudog
 
 type XRegPerG = xRegPerG
 
+type GSchedStats = gSchedStats
+
+const SchedStatsEnabled = schedStatsEnabled
+
 func Getg() *G {
 	return getg()
 }
@@ -465,6 +465,103 @@ func initMetrics() {
 					in.sysStats.gcMiscSys + in.sysStats.otherSys
 			},
 		},
+		"/sched/custom/context-switches/involuntary:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.nivcsw
+			},
+		},
+		"/sched/custom/context-switches/voluntary:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.nvcsw
+			},
+		},
+		"/sched/custom/dequeues/global:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.counts.globalDequeues
+			},
+		},
+		"/sched/custom/dequeues/local:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.counts.localDequeues
+			},
+		},
+		"/sched/custom/observers/dropped:events": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = schedObservers.dropped.Load()
+			},
+		},
+		"/sched/custom/parks:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.counts.parks
+			},
+		},
+		"/sched/custom/preemptions/forced:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.preempts
+			},
+		},
+		"/sched/custom/queue/global/checks:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.counts.globalChecks
+			},
+		},
+		"/sched/custom/steals/attempts:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.stealCalls
+			},
+		},
+		"/sched/custom/steals/hits:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.counts.steals
+			},
+		},
+		"/sched/custom/steals/misses:events": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.stealMisses
+			},
+		},
+		"/sched/queue/global:goroutines": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.globalRunq
+			},
+		},
+		"/sched/queue/local:goroutines": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.localRunq
+			},
+		},
+		"/sched/queue/runnext:goroutines": {
+			deps: makeStatDepSet(schedStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.schedStats.runnext
+			},
+		},
 		"/sched/gomaxprocs:threads": {
 			compute: func(_ *statAggregate, out *metricValue) {
 				out.kind = metricKindUint64
@@ -794,13 +891,24 @@ func (a *finalStatsAggregate) compute() {
 // schedStatsAggregate contains stats about the scheduler, including
 // an approximate count of goroutines in each state.
 type schedStatsAggregate struct {
-	gTotal    uint64
-	gRunning  uint64
-	gRunnable uint64
-	gNonGo    uint64
-	gWaiting  uint64
-	gCreated  uint64
-	threads   uint64
+	gTotal     uint64
+	gRunning   uint64
+	gRunnable  uint64
+	gNonGo     uint64
+	gWaiting   uint64
+	gCreated   uint64
+	threads    uint64
+	nvcsw      uint64
+	nivcsw     uint64
+	preempts   uint64
+	localRunq  uint64
+	globalRunq uint64
+	runnext    uint64
+
+	// Counts behind SchedulerMetrics and the steal statistics.
+	counts      schedCounts
+	stealCalls  uint64
+	stealMisses uint64
 }
 
 // compute populates the schedStatsAggregate with values from the runtime.
@@ -817,11 +925,23 @@ func (a *schedStatsAggregate) compute() {
 
 	// Collect running/runnable from per-P run queues.
 	a.gCreated += sched.goroutinesCreated.Load()
+	a.nvcsw += sched.nvcsw.Load()
+	a.nivcsw += sched.nivcsw.Load()
+	a.preempts += sched.asyncPreempts.Load()
+	a.counts.add(&sched.schedCounts)
+	a.stealCalls += sched.stealCalls.Load()
+	a.stealMisses += sched.stealMisses.Load()
 	for _, p := range allp {
 		if p == nil || p.status == _Pdead {
 			break
 		}
 		a.gCreated += p.goroutinesCreated
+		a.nvcsw += p.nvcsw
+		a.nivcsw += p.nivcsw
+		a.preempts += p.asyncPreempts
+		a.counts.add(&p.schedCounts)
+		a.stealCalls += p.stealCalls
+		a.stealMisses += p.stealMisses
 		switch p.status {
 		case _Prunning:
 			if thread, ok := setBlockOnExitSyscall(p); ok {
@@ -845,8 +965,10 @@ func (a *schedStatsAggregate) compute() {
 			if atomic.Load(&p.runqhead) != h || runnable < 0 {
 				continue
 			}
+			a.localRunq += uint64(runnable)
 			if next != 0 {
 				runnable++
+				a.runnext++
 			}
 			a.gRunnable += uint64(runnable)
 			break
@@ -855,6 +977,7 @@ func (a *schedStatsAggregate) compute() {
 
 	// Global run queue.
 	a.gRunnable += uint64(sched.runq.size)
+	a.globalRunq = uint64(sched.runq.size)
 
 	// Account for Gs that are in _Gsyscall without a P.
 	nGsyscallNoP := sched.nGsyscallNoP.Load()
@@ -432,6 +432,72 @@ var allDesc = []Description{
 		Description: "All memory mapped by the Go runtime into the current process as read-write. Note that this does not include memory mapped by code called via cgo or via the syscall package. Sum of all metrics in /memory/classes.",
 		Kind:        KindUint64,
 	},
+	{
+		Name:        "/sched/custom/context-switches/involuntary:events",
+		Description: "Count of times a goroutine was descheduled because it was preempted. See runtime.ContextSwitches.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/context-switches/voluntary:events",
+		Description: "Count of times a goroutine was descheduled because it blocked or yielded. See runtime.ContextSwitches.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/dequeues/global:events",
+		Description: "Count of goroutines taken from the global run queue, including those moved to a local run queue in a batch. See runtime.SchedulerMetrics.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/dequeues/local:events",
+		Description: "Count of goroutines a P took from its own local run queue to run. See runtime.SchedulerMetrics.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/observers/dropped:events",
+		Description: "Count of scheduling decisions that were not delivered to schedule observers because the observers did not keep up. See runtime.RegisterScheduleObserver.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/parks:events",
+		Description: "Count of times a goroutine blocked, for example on a channel operation, a mutex, or I/O. See runtime.SchedulerMetrics.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/preemptions/forced:events",
+		Description: "Count of times a goroutine was preempted asynchronously because it did not reach a cooperative preemption point in time. See runtime.ForcedPreemptions.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/queue/global/checks:events",
+		Description: "Count of times a P locked the global run queue to take goroutines from it. See runtime.SchedulerMetrics.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/steals/attempts:events",
+		Description: "Count of times a P with no work looked for goroutines to steal from other Ps.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/steals/hits:events",
+		Description: "Count of times a P stole goroutines from another P's local run queue. See runtime.SchedulerMetrics.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/custom/steals/misses:events",
+		Description: "Count of times a P looked for goroutines to steal from every other P and found none.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
 	{
 		Name:        "/sched/gomaxprocs:threads",
 		Description: "The current runtime.GOMAXPROCS setting, or the number of operating system threads that can execute user-level Go code simultaneously.",
@@ -498,6 +564,21 @@ var allDesc = []Description{
 		Kind:        KindFloat64Histogram,
 		Cumulative:  true,
 	},
+	{
+		Name:        "/sched/queue/global:goroutines",
+		Description: "Approximate count of goroutines in the global run queue.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/queue/local:goroutines",
+		Description: "Approximate count of goroutines in the local run queues of all Ps. Goroutines in the Ps' runnext slots are counted by /sched/queue/runnext:goroutines instead.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/queue/runnext:goroutines",
+		Description: "Approximate count of Ps with a goroutine in their runnext slot, which holds the goroutine a P runs before those in its local run queue, typically one just readied by the goroutine running on that P.",
+		Kind:        KindUint64,
+	},
 	{
 		Name:        "/sched/threads/total:threads",
 		Description: "The current count of live threads that are owned by the Go runtime.",
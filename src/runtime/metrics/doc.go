@@ -509,6 +509,53 @@ Below is the full list of supported metrics, ordered lexicographically.
 		by code called via cgo or via the syscall package. Sum of all
 		metrics in /memory/classes.
 
+	/sched/custom/context-switches/involuntary:events
+		Count of times a goroutine was descheduled because it was
+		preempted. See runtime.ContextSwitches.
+
+	/sched/custom/context-switches/voluntary:events
+		Count of times a goroutine was descheduled because it blocked or
+		yielded. See runtime.ContextSwitches.
+
+	/sched/custom/dequeues/global:events
+		Count of goroutines taken from the global run queue,
+		including those moved to a local run queue in a batch.
+		See runtime.SchedulerMetrics.
+
+	/sched/custom/dequeues/local:events
+		Count of goroutines a P took from its own local run queue to
+		run. See runtime.SchedulerMetrics.
+
+	/sched/custom/observers/dropped:events
+		Count of scheduling decisions that were not delivered to
+		schedule observers because the observers did not keep up.
+		See runtime.RegisterScheduleObserver.
+
+	/sched/custom/parks:events
+		Count of times a goroutine blocked, for example on a channel
+		operation, a mutex, or I/O. See runtime.SchedulerMetrics.
+
+	/sched/custom/preemptions/forced:events
+		Count of times a goroutine was preempted asynchronously because
+		it did not reach a cooperative preemption point in time.
+		See runtime.ForcedPreemptions.
+
+	/sched/custom/queue/global/checks:events
+		Count of times a P locked the global run queue to take
+		goroutines from it. See runtime.SchedulerMetrics.
+
+	/sched/custom/steals/attempts:events
+		Count of times a P with no work looked for goroutines to steal
+		from other Ps.
+
+	/sched/custom/steals/hits:events
+		Count of times a P stole goroutines from another P's local run
+		queue. See runtime.SchedulerMetrics.
+
+	/sched/custom/steals/misses:events
+		Count of times a P looked for goroutines to steal from every
+		other P and found none.
+
 	/sched/gomaxprocs:threads
 		The current runtime.GOMAXPROCS setting, or the number of
 		operating system threads that can execute user-level Go code
@@ -577,6 +624,20 @@ Below is the full list of supported metrics, ordered lexicographically.
 		/sched/pauses/stopping/other:seconds). Bucket counts increase
 		monotonically.
 
+	/sched/queue/global:goroutines
+		Approximate count of goroutines in the global run queue.
+
+	/sched/queue/local:goroutines
+		Approximate count of goroutines in the local run queues of
+		all Ps. Goroutines in the Ps' runnext slots are counted by
+		/sched/queue/runnext:goroutines instead.
+
+	/sched/queue/runnext:goroutines
+		Approximate count of Ps with a goroutine in their runnext slot,
+		which holds the goroutine a P runs before those in its local run
+		queue, typically one just readied by the goroutine running on
+		that P.
+
 	/sched/threads/total:threads
 		The current count of live threads that are owned by the Go
 		runtime.
@@ -1584,3 +1584,126 @@ func TestReadMetricsSched(t *testing.T) {
 		t.Fatalf("output:\n%s\n\nwanted:\n%s", output, want)
 	}
 }
+
+func TestReadMetricsSchedCustom(t *testing.T) {
+	var samples []metrics.Sample
+	for _, d := range metrics.All() {
+		if !strings.HasPrefix(d.Name, "/sched/custom/") {
+			continue
+		}
+		if d.Kind != metrics.KindUint64 || !d.Cumulative {
+			t.Errorf("%s: want a cumulative uint64 counter", d.Name)
+		}
+		samples = append(samples, metrics.Sample{Name: d.Name})
+	}
+	if len(samples) == 0 {
+		t.Fatal("found no /sched/custom metrics")
+	}
+	before := slices.Clone(samples)
+	metrics.Read(before)
+
+	// Block and wake a goroutine repeatedly.
+	const N = 100
+	c := make(chan int)
+	done := make(chan bool)
+	go func() {
+		for range c {
+		}
+		done <- true
+	}()
+	for i := 0; i < N; i++ {
+		c <- i
+	}
+	close(c)
+	<-done
+
+	after := slices.Clone(samples)
+	metrics.Read(after)
+	for i := range after {
+		v0, v1 := before[i].Value.Uint64(), after[i].Value.Uint64()
+		if v1 < v0 {
+			t.Errorf("%s decreased: %d -> %d", after[i].Name, v0, v1)
+		}
+		switch after[i].Name {
+		case "/sched/custom/context-switches/voluntary:events",
+			"/sched/custom/parks:events":
+			if v1-v0 < N {
+				t.Errorf("%s: got %d new events, want at least %d", after[i].Name, v1-v0, N)
+			}
+		case "/sched/custom/dequeues/local:events":
+			if v1 == v0 {
+				t.Errorf("%s: got no new events", after[i].Name)
+			}
+		}
+	}
+}
+
+func TestReadMetricsSteals(t *testing.T) {
+	const procs = 4
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+	s := []metrics.Sample{
+		{Name: "/sched/custom/steals/attempts:events"},
+		{Name: "/sched/custom/steals/hits:events"},
+		{Name: "/sched/custom/steals/misses:events"},
+	}
+	read := func() (attempts, hits, misses uint64) {
+		metrics.Read(s)
+		return s[0].Value.Uint64(), s[1].Value.Uint64(), s[2].Value.Uint64()
+	}
+
+	attempts0, hits0, misses0 := read()
+	stealFromPinnedP(100, 100*time.Microsecond, 0)
+	attempts1, hits1, misses1 := read()
+
+	attempts, hits, misses := attempts1-attempts0, hits1-hits0, misses1-misses0
+	if hits == 0 {
+		t.Errorf("no steal hits while one P created work for %d Ps", procs)
+	}
+	// Attempts cut short count as neither hits nor misses.
+	if hits+misses > attempts {
+		t.Errorf("got %d hits and %d misses in only %d attempts", hits, misses, attempts)
+	}
+}
+
+func TestReadMetricsSchedQueue(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	s := []metrics.Sample{
+		{Name: "/sched/queue/local:goroutines"},
+		{Name: "/sched/queue/global:goroutines"},
+		{Name: "/sched/queue/runnext:goroutines"},
+	}
+
+	// With a single P, new goroutines stay queued until we block.
+	// More than fit in the local run queue spill into the global
+	// run queue.
+	const N = 400
+	c := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(N)
+	for i := 0; i < N; i++ {
+		go func() {
+			defer wg.Done()
+			<-c
+		}()
+	}
+	metrics.Read(s)
+	close(c)
+	wg.Wait()
+
+	local, global, next := s[0].Value.Uint64(), s[1].Value.Uint64(), s[2].Value.Uint64()
+	if local+global+next < N {
+		t.Errorf("got %d local, %d global and %d runnext queued goroutines, want at least %d in total", local, global, next, N)
+	}
+	// The last goroutine created is in the runnext slot.
+	if next != 1 && !runtime.RandomizeScheduler {
+		t.Errorf("got %d goroutines in runnext slots, want 1", next)
+	}
+	if global == 0 {
+		t.Errorf("got no goroutines in the global run queue after overflowing the local run queue")
+	}
+	if local == 0 {
+		t.Errorf("got no goroutines in the local run queues")
+	}
+}
@@ -1387,13 +1387,16 @@ func goroutineProfileWithLabelsConcurrent(p []profilerecord.StackRecord, labels
 	// alive and permanently marked as "system". But to make this count agree
 	// with what we'd get from isSystemGoroutine, we need special handling for
 	// goroutines that can vary between user and system to ensure that the count
-	// doesn't change during the collection. So, check the finalizer goroutine
-	// and cleanup goroutines in particular.
+	// doesn't change during the collection. So, check the finalizer goroutine,
+	// cleanup goroutines and schedule observers' goroutine in particular.
 	n = int(gcount(false))
 	if fingStatus.Load()&fingRunningFinalizer != 0 {
 		n++
 	}
 	n += int(gcCleanups.running.Load())
+	if schedObservers.calling.Load() {
+		n++
+	}
 
 	if n > len(p) {
 		// There's not enough space in p to store the whole profile, so (per the
@@ -318,6 +318,8 @@ func asyncPreempt2() {
 	// the system stack right away.
 	mcall(func(gp *g) {
 		gp.asyncSafePoint = true
+		gp.schedStats.countAsyncPreempt()
+		gp.m.p.ptr().asyncPreempts++
 
 		// Move the extended register state from the P to the G. We do this now that
 		// we're on the system stack to avoid stack splits.
@@ -901,6 +901,9 @@ func schedinit() {
 		parseRuntimeDebugVars(gogetenv("GODEBUG"))
 	}
 	finishDebugVarsSetup()
+	if race2enabled {
+		race2parseoptions()
+	}
 	gcinit()
 
 	// Allocate stack space that can be used when crashing due to bad stack
@@ -1468,6 +1471,10 @@ const (
 	stwForTestReadMemStatsSlow                      // "ReadMemStatsSlow (test)"
 	stwForTestPageCachePagesLeaked                  // "PageCachePagesLeaked (test)"
 	stwForTestResetDebugLog                         // "ResetDebugLog (test)"
+	stwForTestRunnableSnapshot                      // "RunnableSnapshot (test)"
+	stwForTestResetStealStats                       // "ResetStealStats (test)"
+	stwSchedDecisionLog                             // "scheduling decision log"
+	stwForTestRunnextStreakLimit                    // "RunnextStreakLimit (test)"
 )
 
 func (r stwReason) String() string {
@@ -1499,6 +1506,10 @@ var stwReasonStrings = [...]string{
 	stwForTestReadMemStatsSlow:     "ReadMemStatsSlow (test)",
 	stwForTestPageCachePagesLeaked: "PageCachePagesLeaked (test)",
 	stwForTestResetDebugLog:        "ResetDebugLog (test)",
+	stwForTestRunnableSnapshot:     "RunnableSnapshot (test)",
+	stwForTestResetStealStats:      "ResetStealStats (test)",
+	stwSchedDecisionLog:            "scheduling decision log",
+	stwForTestRunnextStreakLimit:   "RunnextStreakLimit (test)",
 }
 
 // worldStop provides context from the stop-the-world required by the
@@ -3342,6 +3353,7 @@ func execute(gp *g, inheritTime bool) {
 	if !inheritTime {
 		mp.p.ptr().schedtick++
 	}
+	gp.schedStats.recordRun(mp.p.ptr().id)
 
 	// Check whether the profiler needs to be turned on or off.
 	hz := sched.profilehz
@@ -3416,11 +3428,14 @@ top:
 	// Check the global runnable queue once in a while to ensure fairness.
 	// Otherwise two goroutines can completely occupy the local runqueue
 	// by constantly respawning each other.
-	if pp.schedtick%61 == 0 && !sched.runq.empty() {
+	if pp.schedtick%atomic.Load(&globalRunqInterval) == 0 && !sched.runq.empty() {
 		lock(&sched.lock)
 		gp := globrunqget()
 		unlock(&sched.lock)
+		pp.schedCounts.globalChecks++
 		if gp != nil {
+			pp.schedCounts.globalDequeues++
+			pp.logSchedDecision(schedSourceGlobal, gp)
 			return gp, false, false
 		}
 	}
@@ -3437,12 +3452,21 @@ top:
 		gcCleanups.wake()
 	}
 
+	// Wake up the schedule observers' G.
+	if schedObservers.state.Load()&schedObserversWake != 0 {
+		if gp := wakeScheduleObservers(); gp != nil {
+			ready(gp, 0, true)
+		}
+	}
+
 	if *cgo_yield != nil {
 		asmcgocall(*cgo_yield, nil)
 	}
 
 	// local runq
 	if gp, inheritTime := runqget(pp); gp != nil {
+		pp.schedCounts.localDequeues++
+		pp.logSchedDecision(schedSourceLocal, gp)
 		return gp, inheritTime, false
 	}
 
@@ -3451,7 +3475,10 @@ top:
 		lock(&sched.lock)
 		gp, q := globrunqgetbatch(int32(len(pp.runq)) / 2)
 		unlock(&sched.lock)
+		pp.schedCounts.globalChecks++
 		if gp != nil {
+			pp.schedCounts.globalDequeues += 1 + uint64(q.size)
+			pp.logSchedDecision(schedSourceGlobal, gp)
 			if runqputbatch(pp, &q); !q.empty() {
 				throw("Couldn't put Gs into empty local runq")
 			}
@@ -3578,6 +3605,9 @@ top:
 		if gp == nil {
 			throw("global runq empty with non-zero runqsize")
 		}
+		pp.schedCounts.globalChecks++
+		pp.schedCounts.globalDequeues += 1 + uint64(q.size)
+		pp.logSchedDecision(schedSourceGlobal, gp)
 		if runqputbatch(pp, &q); !q.empty() {
 			throw("Couldn't put Gs into empty local runq")
 		}
@@ -3589,6 +3619,7 @@ top:
 		unlock(&sched.lock)
 		goto top
 	}
+	pp.logSchedDecision(schedSourceIdle, nil)
 	if releasep() != pp {
 		throw("findrunnable: wrong p")
 	}
@@ -3658,6 +3689,9 @@ top:
 				if gp == nil {
 					throw("global runq empty with non-zero runqsize")
 				}
+				pp.schedCounts.globalChecks++
+				pp.schedCounts.globalDequeues += 1 + uint64(q.size)
+				pp.logSchedDecision(schedSourceGlobal, gp)
 				if runqputbatch(pp, &q); !q.empty() {
 					throw("Couldn't put Gs into empty local runq")
 				}
@@ -3803,6 +3837,7 @@ func pollWork() bool {
 // the current time if now was passed as 0.
 func stealWork(now int64) (gp *g, inheritTime bool, rnow, pollUntil int64, newWork bool) {
 	pp := getg().m.p.ptr()
+	pp.stealCalls++
 
 	ranTimer := false
 
@@ -3849,6 +3884,8 @@ func stealWork(now int64) (gp *g, inheritTime bool, rnow, pollUntil int64, newWo
 					// stolen G's. So check now if there
 					// is a local G to run.
 					if gp, inheritTime := runqget(pp); gp != nil {
+						pp.schedCounts.localDequeues++
+						pp.logSchedDecision(schedSourceLocal, gp)
 						return gp, inheritTime, now, pollUntil, ranTimer
 					}
 					ranTimer = true
@@ -3858,6 +3895,10 @@ func stealWork(now int64) (gp *g, inheritTime bool, rnow, pollUntil int64, newWo
 			// Don't bother to attempt to steal if p2 is idle.
 			if !idlepMask.read(enum.position()) {
 				if gp := runqsteal(pp, p2, stealTimersOrRunNextG); gp != nil {
+					pp.stealsFrom[p2.id]++
+					pp.stealHits++
+					pp.schedCounts.steals++
+					pp.logSchedDecision(schedSourceSteal, gp)
 					return gp, false, now, pollUntil, ranTimer
 				}
 			}
@@ -3867,6 +3908,7 @@ func stealWork(now int64) (gp *g, inheritTime bool, rnow, pollUntil int64, newWo
 	// No goroutines found to steal. Regardless, running a timer may have
 	// made some goroutine ready that we missed. Indicate the next timer to
 	// wait for.
+	pp.stealMisses++
 	return nil, false, now, pollUntil, ranTimer
 }
 
@@ -4265,6 +4307,9 @@ func park_m(gp *g) {
 		bubble.decActive()
 	}
 
+	gp.schedStats.countSwitch(false)
+	mp.p.ptr().nvcsw++
+	mp.p.ptr().schedCounts.parks++
 	schedule()
 }
 
@@ -4299,6 +4344,12 @@ func goschedImpl(gp *g, preempted bool) {
 		wakep()
 	}
 
+	gp.schedStats.countSwitch(preempted)
+	if preempted {
+		getg().m.p.ptr().nivcsw++
+	} else {
+		getg().m.p.ptr().nvcsw++
+	}
 	schedule()
 }
 
@@ -4380,6 +4431,8 @@ func preemptPark(gp *g) {
 	if trace.ok() {
 		traceRelease(trace)
 	}
+	gp.schedStats.countSwitch(true)
+	getg().m.p.ptr().nivcsw++
 	schedule()
 }
 
@@ -4417,6 +4470,8 @@ func goyield_m(gp *g) {
 	}
 	dropg()
 	runqput(pp, gp, false)
+	gp.schedStats.countSwitch(false)
+	pp.nvcsw++
 	schedule()
 }
 
@@ -5267,6 +5322,7 @@ func newproc1(fn *funcval, callergp *g, callerpc uintptr, parked bool, waitreaso
 	newg.ancestors = saveAncestors(callergp)
 	newg.startpc = fn.fn
 	newg.runningCleanups.Store(false)
+	newg.schedStats = gSchedStats{}
 	if isSystemGoroutine(newg, false) {
 		sched.ngsys.Add(1)
 	} else {
@@ -5960,6 +6016,20 @@ func (pp *p) destroy() {
 	pp.cleanupsQueued = 0
 	sched.goroutinesCreated.Add(int64(pp.goroutinesCreated))
 	pp.goroutinesCreated = 0
+	sched.nvcsw.Add(int64(pp.nvcsw))
+	pp.nvcsw = 0
+	sched.nivcsw.Add(int64(pp.nivcsw))
+	pp.nivcsw = 0
+	sched.asyncPreempts.Add(int64(pp.asyncPreempts))
+	pp.asyncPreempts = 0
+	sched.stealCalls.Add(int64(pp.stealCalls))
+	pp.stealCalls = 0
+	sched.stealHits.Add(int64(pp.stealHits))
+	pp.stealHits = 0
+	sched.stealMisses.Add(int64(pp.stealMisses))
+	pp.stealMisses = 0
+	sched.schedCounts.add(&pp.schedCounts)
+	pp.schedCounts = schedCounts{}
 	pp.xRegs.free()
 	pp.status = _Pdead
 }
@@ -6012,6 +6082,7 @@ func procresize(nprocs int32) *p {
 		timerpMask = timerpMask.resize(nprocs)
 		work.spanqMask = work.spanqMask.resize(nprocs)
 		unlock(&allpLock)
+		growSchedDecisionLog(nprocs)
 	}
 
 	// initialize new P's
@@ -6079,6 +6150,15 @@ func procresize(nprocs int32) *p {
 		unlock(&allpLock)
 	}
 
+	// The steal counts are indexed by victim, so start over
+	// whenever the set of victims changes.
+	if old != nprocs {
+		for _, pp := range allp {
+			pp.stealsFrom = make([]uint64, nprocs)
+		}
+		sched.stealsFromGen++
+	}
+
 	var runnablePs *p
 	for i := nprocs - 1; i >= 0; i-- {
 		pp := allp[i]
@@ -7417,17 +7497,42 @@ func runqputbatch(pp *p, q *gQueue) {
 	return
 }
 
+// globalRunqInterval is the number of scheduler ticks between the
+// checks findRunnable makes of the global run queue before the local
+// one.
+//
+// This is a variable for testing purposes. It normally doesn't change.
+// Tests may change it while Ps are running, so it is accessed
+// atomically.
+var globalRunqInterval uint32 = 61
+
+// runnextStreakLimit is the number of consecutive Gs runqget takes
+// from runnext before it takes one from the local run queue instead,
+// if the queue is not empty, or 0 for no limit. Goroutines that keep
+// readying each other share a time slice and are preempted together
+// when it runs out, so the rest of the queue is not starved even
+// without a limit.
+//
+// This is a variable for testing purposes. It normally doesn't change.
+var runnextStreakLimit uint32
+
 // Get g from local runnable queue.
 // If inheritTime is true, gp should inherit the remaining time in the
 // current time slice. Otherwise, it should start a new time slice.
 // Executed only by the owner P.
 func runqget(pp *p) (gp *g, inheritTime bool) {
-	// If there's a runnext, it's the next G to run.
+	// If there's a runnext, it's the next G to run, unless it
+	// has had its turn runnextStreakLimit times in a row.
 	next := pp.runnext
+	limit := runnextStreakLimit
+	passOver := limit != 0 && next != 0 && pp.runnextStreak >= limit
 	// If the runnext is non-0 and the CAS fails, it could only have been stolen by another P,
 	// because other Ps can race to set runnext to 0, but only the current P can set it to non-0.
 	// Hence, there's no need to retry this CAS if it fails.
-	if next != 0 && pp.runnext.cas(next, 0) {
+	if next != 0 && !passOver && pp.runnext.cas(next, 0) {
+		if limit != 0 {
+			pp.runnextStreak++
+		}
 		return next.ptr(), true
 	}
 
@@ -7435,10 +7540,20 @@ func runqget(pp *p) (gp *g, inheritTime bool) {
 		h := atomic.LoadAcq(&pp.runqhead) // load-acquire, synchronize with other consumers
 		t := pp.runqtail
 		if t == h {
+			// Nothing else to run, so take the runnext
+			// we passed over after all, and start a new
+			// streak.
+			if passOver && pp.runnext.cas(next, 0) {
+				pp.runnextStreak = 0
+				return next.ptr(), true
+			}
 			return nil, false
 		}
 		gp := pp.runq[h%uint32(len(pp.runq))].ptr()
 		if atomic.CasRel(&pp.runqhead, h, h+1) { // cas-release, commits consume
+			if limit != 0 {
+				pp.runnextStreak = 0
+			}
 			return gp, false
 		}
 	}
@@ -7683,6 +7798,56 @@ func setMaxThreads(in int) (out int) {
 	return
 }
 
+// readStealMatrix returns the number of successful steals by each P
+// from each P. The result is indexed by thief P ID, then victim P ID.
+// It also returns the generation of the counts, which changes whenever
+// GOMAXPROCS changes and the counts start over.
+//
+//go:linkname readStealMatrix runtime/debug.readStealMatrix
+func readStealMatrix() (m [][]uint64, gen uint64) {
+	for {
+		// Allocate before taking sched.lock, and retry if
+		// the number of Ps changed in the meantime.
+		n := int(gomaxprocs)
+		m = make([][]uint64, n)
+		for i := range m {
+			m[i] = make([]uint64, n)
+		}
+		lock(&sched.lock)
+		if len(allp) != n {
+			unlock(&sched.lock)
+			continue
+		}
+		for i, pp := range allp {
+			copy(m[i], pp.stealsFrom)
+		}
+		gen = sched.stealsFromGen
+		unlock(&sched.lock)
+		return m, gen
+	}
+}
+
+// runnableGoroutines returns the number of non-system goroutines, other
+// than the caller, that are running or waiting for a P to run on.
+// Goroutines in system calls or cgo calls are not counted: they may be
+// blocked in the kernel for arbitrarily long.
+//
+//go:linkname runnableGoroutines runtime/debug.runnableGoroutines
+func runnableGoroutines() (n int) {
+	me := getg()
+	forEachG(func(gp *g) {
+		if gp == me || isSystemGoroutine(gp, false) {
+			return
+		}
+		switch readgstatus(gp) &^ _Gscan {
+		case _Grunnable, _Grunning, _Gpreempted,
+			_Gcopystack: // running, and growing its stack
+			n++
+		}
+	})
+	return
+}
+
 // procPin should be an internal detail,
 // but widely used packages access it using linkname.
 // Notable members of the hall of shame include:
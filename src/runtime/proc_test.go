@@ -12,6 +12,7 @@ import (
 	"net"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -366,6 +367,81 @@ func TestPreemptionGC(t *testing.T) {
 	atomic.StoreUint32(&stop, 1)
 }
 
+func TestForcedPreemptions(t *testing.T) {
+	if !runtime.AsyncPreemptEnabled() {
+		t.Skip("asynchronous preemption is disabled")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	total0 := runtime.ForcedPreemptions()
+
+	// With a single P, the hog only stops running when it is
+	// preempted, which it can only be asynchronously.
+	var stop atomic.Bool
+	hogID := make(chan uint64, 1)
+	coopID := make(chan uint64, 1)
+	release := make(chan bool)
+	defer close(release)
+	go func() {
+		for !stop.Load() {
+		}
+		hogID <- runtime.Goid()
+		<-release
+	}()
+	go func() {
+		for !stop.Load() {
+			time.Sleep(time.Millisecond)
+		}
+		coopID <- runtime.Goid()
+		<-release
+	}()
+	time.Sleep(50 * time.Millisecond)
+	stop.Store(true)
+
+	hogid, coopid := <-hogID, <-coopID
+	total := runtime.ForcedPreemptions() - total0
+	if total == 0 {
+		t.Errorf("hog goroutine was never preempted asynchronously")
+	}
+	if _, ok := runtime.GoroutineForcedPreemptions(math.MaxUint64); ok {
+		t.Errorf("found forced preemptions for a missing goroutine")
+	}
+	if !runtime.SchedStatsEnabled {
+		if _, ok := runtime.GoroutineForcedPreemptions(hogid); ok {
+			t.Errorf("found per-goroutine forced preemptions without -tags schedstats")
+		}
+		return
+	}
+
+	hog, ok := runtime.GoroutineForcedPreemptions(hogid)
+	if !ok {
+		t.Fatal("hog goroutine not found")
+	}
+	coop, ok := runtime.GoroutineForcedPreemptions(coopid)
+	if !ok {
+		t.Fatal("cooperative goroutine not found")
+	}
+	if hog == 0 {
+		t.Errorf("hog goroutine was never preempted asynchronously")
+	}
+	if coop >= hog {
+		t.Errorf("cooperative goroutine was preempted asynchronously %d times, hog only %d", coop, hog)
+	}
+	if total < hog {
+		t.Errorf("ForcedPreemptions grew by %d, less than the hog's %d", total, hog)
+	}
+}
+
+func TestAsyncPreemptEnabled(t *testing.T) {
+	want := runtime.PreemptMSupported
+	if got := runTestProg(t, "testprog", "AsyncPreemptEnabled"); got != fmt.Sprintln(want) {
+		t.Errorf("AsyncPreemptEnabled() = %s, want %v", strings.TrimSpace(got), want)
+	}
+	got := runTestProg(t, "testprog", "AsyncPreemptEnabled", "GODEBUG=asyncpreemptoff=1")
+	if got != "false\n" {
+		t.Errorf("AsyncPreemptEnabled() = %s with asyncpreemptoff=1, want false", strings.TrimSpace(got))
+	}
+}
+
 func TestAsyncPreempt(t *testing.T) {
 	if !runtime.PreemptMSupported {
 		t.Skip("asynchronous preemption not supported on this platform")
@@ -428,6 +504,498 @@ func TestNumGoroutine(t *testing.T) {
 	}
 }
 
+func TestContextSwitches(t *testing.T) {
+	const n = 100
+	v0, _ := runtime.ContextSwitchesForTest()
+	total0 := runtime.ContextSwitches()
+
+	// Each receive on an empty channel blocks the receiver.
+	c := make(chan int)
+	done := make(chan bool)
+	go func() {
+		for range c {
+		}
+		done <- true
+	}()
+	for i := 0; i < n; i++ {
+		c <- i
+		runtime.Gosched()
+	}
+	close(c)
+	<-done
+
+	v1, _ := runtime.ContextSwitchesForTest()
+	if v1-v0 < n {
+		t.Errorf("got %d voluntary context switches, want at least %d", v1-v0, n)
+	}
+	if total1 := runtime.ContextSwitches(); total1-total0 < v1-v0 {
+		t.Errorf("ContextSwitches grew by %d, less than the %d voluntary switches", total1-total0, v1-v0)
+	}
+}
+
+func TestContextSwitchesInvoluntary(t *testing.T) {
+	if runtime.GOARCH == "wasm" {
+		t.Skip("no preemption on wasm yet")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	_, iv0 := runtime.ContextSwitchesForTest()
+
+	// With a single P, the spinning goroutine only stops running
+	// once it is preempted, so that the timer can wake us up.
+	var stop atomic.Bool
+	done := make(chan bool)
+	go func() {
+		for !stop.Load() {
+		}
+		done <- true
+	}()
+	time.Sleep(50 * time.Millisecond)
+	stop.Store(true)
+	<-done
+
+	if _, iv1 := runtime.ContextSwitchesForTest(); iv1 == iv0 {
+		t.Errorf("got no involuntary context switches from a spinning goroutine")
+	}
+}
+
+func TestGoroutineContextSwitches(t *testing.T) {
+	if !runtime.SchedStatsEnabled {
+		t.Skip("per-goroutine counts need -tags schedstats")
+	}
+	const n = 10
+	goid := make(chan uint64)
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < n; i++ {
+			runtime.Gosched()
+		}
+		goid <- runtime.Goid()
+		<-done
+	}()
+	id := <-goid
+	defer close(done)
+
+	v, _, ok := runtime.GoroutineContextSwitches(id)
+	if !ok {
+		t.Fatalf("GoroutineContextSwitches(%d) did not find the goroutine", id)
+	}
+	if v < n {
+		t.Errorf("goroutine has %d voluntary context switches, want at least %d", v, n)
+	}
+	if _, _, ok := runtime.GoroutineContextSwitches(math.MaxUint64); ok {
+		t.Errorf("found context switches for a missing goroutine")
+	}
+}
+
+func TestRunnextGoroutines(t *testing.T) {
+	if runtime.RandomizeScheduler {
+		t.Skip("race-enabled builds randomize use of the runnext slot")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	// A new goroutine goes into the runnext slot of its creator's P.
+	c := make(chan uint64, 1)
+	go func() {
+		c <- runtime.Goid()
+	}()
+	next := runtime.RunnextGoroutines()
+	goid := <-c
+
+	if len(next) != 1 {
+		t.Fatalf("RunnextGoroutines returned %d Ps, want 1: %v", len(next), next)
+	}
+	if next[0] != goid {
+		t.Errorf("runnext of P 0 = %d, want new goroutine %d", next[0], goid)
+	}
+	if next := runtime.RunnextGoroutines(); next[0] != 0 {
+		t.Errorf("runnext of P 0 = %d after the goroutine ran, want 0", next[0])
+	}
+}
+
+func TestSchedulerTopology(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	topo := runtime.SchedulerTopology()
+	if topo.NumP != procs {
+		t.Errorf("NumP = %d, want GOMAXPROCS %d", topo.NumP, procs)
+	}
+	if len(topo.M) != procs {
+		t.Fatalf("got %d M entries, want %d", len(topo.M), procs)
+	}
+	// We're running, so at least one P has an M.
+	if !slices.ContainsFunc(topo.M, func(id int64) bool { return id >= 0 }) {
+		t.Errorf("no P has an M: %v", topo.M)
+	}
+}
+
+func TestGoroutineRunHistory(t *testing.T) {
+	if !runtime.SchedStatsEnabled {
+		t.Skip("run histories need -tags schedstats")
+	}
+	// run starts a goroutine that calls f, and returns the goroutine's
+	// run history while it is still alive.
+	run := func(f func()) []int {
+		c := make(chan uint64, 1)
+		release := make(chan bool)
+		defer close(release)
+		go func() {
+			f()
+			c <- runtime.Goid()
+			<-release
+		}()
+		return runtime.GoroutineRunHistory(<-c)
+	}
+
+	t.Run("OneP", func(t *testing.T) {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+		hist := run(func() {
+			for i := 0; i < 10; i++ {
+				runtime.Gosched()
+			}
+		})
+		if !slices.Equal(hist, []int{0}) {
+			t.Errorf("run history = %v, want [0]", hist)
+		}
+	})
+
+	t.Run("Migrate", func(t *testing.T) {
+		const procs = 4
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		var seen []int
+		hist := run(func() {
+			for i := 0; i < 100; i++ {
+				time.Sleep(10 * time.Microsecond)
+				pid := runtime.ProcPin()
+				runtime.ProcUnpin()
+				if len(seen) == 0 || seen[len(seen)-1] != pid {
+					seen = append(seen, pid)
+				}
+			}
+		})
+		if len(hist) == 0 || len(hist) > 8 {
+			t.Fatalf("run history = %v, want 1 to 8 entries", hist)
+		}
+		if len(hist) < min(len(seen), 8) {
+			t.Errorf("run history = %v, shorter than the Ps observed: %v", hist, seen)
+		}
+		if hist[len(hist)-1] != seen[len(seen)-1] {
+			t.Errorf("run history = %v, want last P %d", hist, seen[len(seen)-1])
+		}
+		for i, pid := range hist {
+			if pid < 0 || pid >= procs {
+				t.Errorf("run history = %v, P %d out of range", hist, pid)
+			}
+			if i > 0 && hist[i-1] == pid {
+				t.Errorf("run history = %v, repeats P %d", hist, pid)
+			}
+		}
+	})
+
+	if hist := runtime.GoroutineRunHistory(math.MaxUint64); hist != nil {
+		t.Errorf("run history of missing goroutine = %v, want nil", hist)
+	}
+}
+
+// spinFor busy-waits for d without blocking or yielding.
+func spinFor(d time.Duration) {
+	end := time.Now().Add(d)
+	for time.Now().Before(end) {
+	}
+}
+
+// stealFromPinnedP starts n goroutines that each spin for work while
+// the calling goroutine is pinned to its P, waiting gap between them,
+// so that the other Ps can only get to the goroutines by stealing.
+// It waits for the goroutines to finish and returns the pinned P's ID.
+func stealFromPinnedP(n int, work, gap time.Duration) int {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	pid := runtime.ProcPin()
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			spinFor(work)
+		}()
+		spinFor(gap)
+	}
+	runtime.ProcUnpin()
+	wg.Wait()
+	return pid
+}
+
+func TestStealMatrix(t *testing.T) {
+	const procs = 4
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+	column := func(m [][]uint64, victim int) (n uint64) {
+		for _, row := range m {
+			n += row[victim]
+		}
+		return n
+	}
+
+	before := runtime.StealMatrixForTest()
+
+	// Trickle work onto the producer's P, so that the other Ps rarely
+	// build up a backlog of their own to be stolen from.
+	producer := stealFromPinnedP(200, 10*time.Microsecond, 50*time.Microsecond)
+
+	after := runtime.StealMatrixForTest()
+	if len(after) != procs {
+		t.Fatalf("steal matrix has %d rows, want %d", len(after), procs)
+	}
+	for i := range after {
+		if after[i][i] != before[i][i] {
+			t.Errorf("P %d stole from itself", i)
+		}
+	}
+	stolen := column(after, producer) - column(before, producer)
+	if stolen == 0 {
+		t.Fatalf("no steals from producer P %d: %v", producer, after)
+	}
+	for victim := 0; victim < procs; victim++ {
+		if victim == producer {
+			continue
+		}
+		if other := column(after, victim) - column(before, victim); other > stolen {
+			t.Errorf("P %d was stolen from %d times, more than producer P %d's %d", victim, other, producer, stolen)
+		}
+	}
+}
+
+func TestStealStats(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	runtime.ResetStealStatsForTest()
+	stealFromPinnedP(100, 100*time.Microsecond, 0)
+
+	st := runtime.StealStatsForTest()
+	if st.Successes == 0 {
+		t.Errorf("no successful steals: %+v", st)
+	}
+	if st.Successes+st.Failures > st.Calls {
+		t.Errorf("more steal outcomes than calls: %+v", st)
+	}
+}
+
+func TestReadSchedulerMetrics(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	var before runtime.SchedulerMetrics
+	runtime.ReadSchedulerMetrics(&before)
+
+	// Ping-pong over an unbuffered channel, parking each side in turn.
+	const rounds = 100
+	c := make(chan int)
+	done := make(chan bool)
+	go func() {
+		for range c {
+		}
+		done <- true
+	}()
+	for i := 0; i < rounds; i++ {
+		c <- i
+	}
+	close(c)
+	<-done
+
+	// Create more goroutines than fit in the local run queue, so
+	// that the overflow is put on the global run queue.
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go wg.Done()
+	}
+	wg.Wait()
+
+	var after runtime.SchedulerMetrics
+	runtime.ReadSchedulerMetrics(&after)
+
+	if after.Parks < before.Parks+rounds {
+		t.Errorf("Parks went from %d to %d, want an increase of at least %d", before.Parks, after.Parks, rounds)
+	}
+	if after.LocalDequeues <= before.LocalDequeues {
+		t.Errorf("LocalDequeues went from %d to %d, want an increase", before.LocalDequeues, after.LocalDequeues)
+	}
+	if after.GlobalQueueChecks <= before.GlobalQueueChecks {
+		t.Errorf("GlobalQueueChecks went from %d to %d, want an increase", before.GlobalQueueChecks, after.GlobalQueueChecks)
+	}
+	if after.GlobalDequeues <= before.GlobalDequeues {
+		t.Errorf("GlobalDequeues went from %d to %d, want an increase", before.GlobalDequeues, after.GlobalDequeues)
+	}
+}
+
+func TestSchedDecisionLog(t *testing.T) {
+	const procs = 4
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+	runtime.EnableDecisionLogForTest(true)
+	var wg sync.WaitGroup
+	runtime.ProcPin()
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			end := time.Now().Add(50 * time.Microsecond)
+			for time.Now().Before(end) {
+			}
+		}()
+	}
+	runtime.ProcUnpin()
+	wg.Wait()
+	runtime.EnableDecisionLogForTest(false)
+
+	log := runtime.DecisionLogForTest()
+	if len(log) == 0 {
+		t.Fatal("no scheduling decisions logged")
+	}
+	sources := make(map[string]int)
+	for i, d := range log {
+		sources[d.Source]++
+		switch d.Source {
+		case "local", "global", "steal":
+			if d.Goid == 0 {
+				t.Errorf("decision %d from %s has no goroutine: %+v", i, d.Source, d)
+			}
+		case "idle":
+			if d.Goid != 0 {
+				t.Errorf("idle decision %d has a goroutine: %+v", i, d)
+			}
+		default:
+			t.Errorf("decision %d has invalid source: %+v", i, d)
+		}
+		if d.P < 0 || d.P >= procs {
+			t.Errorf("decision %d has invalid P: %+v", i, d)
+		}
+		if i > 0 && d.When < log[i-1].When {
+			t.Errorf("decision %d is out of order: %+v after %+v", i, d, log[i-1])
+		}
+	}
+	if sources["local"] == 0 {
+		t.Errorf("no decisions from the local run queue: %v", sources)
+	}
+}
+
+func TestScheduleObserver(t *testing.T) {
+	for _, name := range []string{"ScheduleObserver", "ScheduleObserverDrops", "ScheduleObserverUnregister"} {
+		t.Run(name, func(t *testing.T) {
+			output := runTestProg(t, "testprog", name)
+			if want := "OK\n"; output != want {
+				t.Fatalf("want %q, got:\n%s", want, output)
+			}
+		})
+	}
+}
+
+func TestScheduleObserverDeadlock(t *testing.T) {
+	testDeadlock(t, "ScheduleObserverDeadlock")
+}
+
+func TestRunnableSnapshot(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	before := make(map[uint64]bool)
+	for _, r := range runtime.RunnableSnapshot() {
+		before[r.Goid] = true
+	}
+
+	// With a single P, new goroutines stay in the run queues until
+	// this goroutine blocks. Create more than fit in the local run
+	// queue, so that some overflow to the global run queue.
+	const n = 300
+	start := make(chan bool)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+		}()
+	}
+	snap := runtime.RunnableSnapshot()
+	close(start)
+	wg.Wait()
+
+	var created, global, runnext int
+	var maxGoid, runnextGoid uint64
+	for _, r := range snap {
+		if r.PID != 0 && r.PID != -1 {
+			t.Errorf("goroutine %d in run queue of P %d with GOMAXPROCS=1", r.Goid, r.PID)
+		}
+		if r.IsRunnext {
+			runnext++
+			runnextGoid = r.Goid
+		}
+		if before[r.Goid] {
+			continue
+		}
+		created++
+		maxGoid = max(maxGoid, r.Goid)
+		if r.PID == -1 {
+			global++
+		}
+	}
+	if created < n {
+		t.Errorf("snapshot has %d new goroutines, want at least %d", created, n)
+	}
+	if global == 0 {
+		t.Errorf("snapshot has no new goroutines in the global run queue")
+	}
+	if runtime.RandomizeScheduler {
+		// Which goroutine, if any, is in runnext is random.
+		return
+	}
+	if runnext != 1 {
+		t.Errorf("snapshot has %d runnext goroutines, want 1", runnext)
+	} else if runnextGoid != maxGoid {
+		t.Errorf("runnext is goroutine %d, want the last one created, %d", runnextGoid, maxGoid)
+	}
+}
+
+func TestRunnextStreakLimit(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	defer runtime.SetRunnextStreakLimitForTest(runtime.SetRunnextStreakLimitForTest(4))
+
+	ping := make(chan bool)
+	pong := make(chan bool)
+	go func() {
+		for range ping {
+			pong <- true
+		}
+	}()
+	defer close(ping)
+	ping <- true
+	<-pong
+	// Let the ponger block on ping again.
+	runtime.Gosched()
+
+	// The new goroutine starts in runnext, and is moved to the local
+	// run queue by the next ping, which readies the ponger into
+	// runnext. From then on, this goroutine and the ponger hand the P
+	// to each other through runnext, sharing a time slice that without
+	// a streak limit lasts thousands of rounds.
+	var ran atomic.Bool
+	go func() {
+		ran.Store(true)
+	}()
+	const maxRounds = 100
+	for rounds := 0; !ran.Load(); rounds++ {
+		if rounds == maxRounds {
+			t.Fatalf("queued goroutine did not run within %d ping-pong rounds", maxRounds)
+		}
+		ping <- true
+		<-pong
+	}
+}
+
+func TestRunnextStreakReset(t *testing.T) {
+	if runtime.RandomizeScheduler {
+		t.Skip("race-enabled builds randomize use of the runnext slot")
+	}
+	defer runtime.SetRunnextStreakLimitForTest(runtime.SetRunnextStreakLimitForTest(1))
+	runtime.RunRunnextStreakTest()
+}
+
 func TestPingPongHog(t *testing.T) {
 	if runtime.GOARCH == "wasm" {
 		t.Skip("no preemption on wasm yet")
@@ -647,6 +1215,17 @@ func TestSchedLocalQueueEmpty(t *testing.T) {
 	runtime.RunSchedLocalQueueEmptyTest(iters)
 }
 
+func TestSetGlobalQueueInterval(t *testing.T) {
+	old := runtime.SetGlobalQueueIntervalForTest(7)
+	defer runtime.SetGlobalQueueIntervalForTest(old)
+	if got := runtime.SetGlobalQueueIntervalForTest(0); got != 7 {
+		t.Errorf("previous interval = %d, want 7", got)
+	}
+	if got := runtime.SetGlobalQueueIntervalForTest(old); got != 61 {
+		t.Errorf("interval 0 selected %d, want the default 61", got)
+	}
+}
+
 func benchmarkStackGrowth(b *testing.B, rec int) {
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -756,6 +1335,26 @@ func BenchmarkCreateGoroutinesSingle(b *testing.B) {
 	wg.Wait()
 }
 
+func BenchmarkGlobalQueueInterval(b *testing.B) {
+	for _, n := range []int32{1, 7, 31, 61, 127, 1021} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			defer runtime.SetGlobalQueueIntervalForTest(runtime.SetGlobalQueueIntervalForTest(n))
+
+			// Start more goroutines at once than fit in a local
+			// run queue, so that some overflow to the global one.
+			const batch = 1000
+			var wg sync.WaitGroup
+			for i := 0; i < b.N; i += batch {
+				wg.Add(batch)
+				for range batch {
+					go wg.Done()
+				}
+				wg.Wait()
+			}
+		})
+	}
+}
+
 func BenchmarkClosureCall(b *testing.B) {
 	sum := 0
 	off1 := 1
@@ -16,6 +16,7 @@ func race2ReadObjectPC(t *_type, addr unsafe.Pointer, callerpc, pc uintptr)  { t
 func race2WriteObjectPC(t *_type, addr unsafe.Pointer, callerpc, pc uintptr) { throw("race") }
 func race2init() (uintptr, uintptr)                                          { throw("race"); return 0, 0 }
 func race2fini()                                                             { throw("race") }
+func race2parseoptions()                                                     { throw("race") }
 func race2proccreate() uintptr                                               { throw("race"); return 0 }
 func race2procdestroy(ctx uintptr)                                           { throw("race") }
 func race2mapshadow(addr unsafe.Pointer, size uintptr)                       { throw("race") }
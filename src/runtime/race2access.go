@@ -0,0 +1,394 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime
+
+import (
+	"internal/bytealg"
+	"internal/runtime/atomic"
+	"internal/runtime/sys"
+	"internal/strconv"
+	"unsafe"
+)
+
+// Race detection for the race2 detector.
+//
+// The runtime reports accesses to the objects it implements, such as
+// maps and channels, through race2readpc and race2writepc. For every
+// 8-byte word accessed this way, an access record remembers the last
+// write and the reads since then: which goroutine made them, at which
+// epoch, and from which call site. An access that conflicts with a
+// recorded one that does not happen before it, by the vector clocks of
+// race2clock.go, is a data race, and is reported on stderr.
+//
+// As in FastTrack, a read drops the recorded reads that happen before
+// it, since a write ordered after the new read is ordered after them
+// too. The reads that remain are concurrent with each other, and up to
+// race2WordReads of them are kept. If more goroutines read a word
+// concurrently, the oldest read is forgotten, and a later write that
+// races only with it goes unreported.
+//
+// Unwinding the stack on every access would make every map operation
+// far slower, so only the call site is recorded. The full stack of the
+// current access is printed when a race is found, but the earlier
+// access is shown by its call site alone.
+//
+// Records live in a hash table like sync variables, and each bucket
+// has its own lock, so that accesses to different words rarely
+// contend. The race2ShadowAccess bit is set in the shadow of a word that has a
+// record, so that race2malloc and race2free can drop the records of
+// memory that is reused without taking the lock for every object.
+// Only words whose shadow is mapped are tracked.
+//
+// Each pair of racing call sites is reported once. With
+// GORACE=halt_on_error=1, the program exits with status 66 after the
+// first report, like with the race detector. Synchronization
+// through packages sync and sync/atomic is seen through race2sync.go.
+
+const (
+	// race2ShadowAccess is set in the shadow byte of a word that has
+	// an access record.
+	race2ShadowAccess = 1 << 1
+
+	// race2WordReads is the number of concurrent reads recorded for
+	// a word.
+	race2WordReads = 4
+)
+
+// race2Access describes one access to a word.
+type race2Access struct {
+	goid  uint64
+	epoch uint64 // 0 if there is no access
+	slot  uint32
+	write bool
+
+	// pc is the runtime function that made the access on behalf of
+	// its caller, and callerpc is the return PC into the caller.
+	pc       uintptr
+	callerpc uintptr
+}
+
+// race2Word is the access record of a word.
+type race2Word struct {
+	_     sys.NotInHeap
+	addr  uintptr
+	write race2Access
+	reads [race2WordReads]race2Access // concurrent reads, oldest first
+	next  *race2Word                  // in the hash bucket, or on race2accesses.free
+}
+
+// race2WordBucket is a hash bucket of access records.
+type race2WordBucket struct {
+	// lock is a leaf lock protecting the records in the bucket.
+	lock  mutex
+	words *race2Word
+}
+
+var race2accesses struct {
+	buckets [1 << race2SyncBucketBits]race2WordBucket
+
+	// lock is a leaf lock protecting the fields below.
+	lock mutex
+
+	free *race2Word
+
+	// reported holds the call site pairs that were reported, to
+	// report each race once. When it is full, races are reported
+	// every time.
+	reported  [256][2]uintptr
+	nreported int
+
+	// reports counts the races found.
+	reports int
+}
+
+// race2exitOnError makes the program exit after reporting a race.
+var race2exitOnError atomic.Bool
+
+// race2reportBuf, if not nil, receives race reports instead of stderr,
+// up to its capacity.
+// This is a variable for testing purposes. It normally doesn't change.
+var race2reportBuf *[]byte
+
+// race2SetExitOnError sets whether the program exits with status 66,
+// the exit status of the race detector, after reporting a race.
+func race2SetExitOnError(exit bool) {
+	race2exitOnError.Store(exit)
+}
+
+// race2parseoptions applies the options in the GORACE environment
+// variable that race2 supports. Like the race detector, it takes a
+// space-separated list of name=value pairs, and halt_on_error=1 makes
+// the program exit after the first race it reports. Other options
+// are ignored.
+func race2parseoptions() {
+	for p := gogetenv("GORACE"); p != ""; {
+		var field string
+		if i := bytealg.IndexByteString(p, ' '); i < 0 {
+			field, p = p, ""
+		} else {
+			field, p = p[:i], p[i+1:]
+		}
+		i := bytealg.IndexByteString(field, '=')
+		if i < 0 || field[:i] != "halt_on_error" {
+			continue
+		}
+		if n, err := strconv.Atoi(field[i+1:]); err == nil {
+			race2SetExitOnError(n != 0)
+		}
+	}
+}
+
+func race2readpc(addr unsafe.Pointer, callerpc, pc uintptr) {
+	race2access(addr, callerpc, pc, false)
+}
+
+func race2writepc(addr unsafe.Pointer, callerpc, pc uintptr) {
+	race2access(addr, callerpc, pc, true)
+}
+
+func race2malloc(p unsafe.Pointer, sz uintptr) {
+	race2forget(uintptr(p), sz)
+}
+
+func race2free(p unsafe.Pointer, sz uintptr) {
+	race2forget(uintptr(p), sz)
+}
+
+// race2access checks an access by the current goroutine to the word
+// containing addr against the recorded accesses, reports a race if it
+// conflicts with one of them, and records it.
+func race2access(addr unsafe.Pointer, callerpc, pc uintptr, write bool) {
+	gp := getg()
+	ctx := race2ctxOf(gp.racectx)
+	word := uintptr(addr) &^ 7
+	if gp.raceignore != 0 || ctx == nil || !race2shadowMapped(word) {
+		return
+	}
+	cur := race2Access{goid: gp.goid, slot: ctx.slot, write: write, pc: pc, callerpc: callerpc}
+
+	var prev race2Access
+	racy := false
+	w, b := race2lockWord(word)
+	cur.epoch = ctx.clock[ctx.slot]
+	if w.write.epoch != 0 && !ctx.sees(&w.write) {
+		prev, racy = w.write, true
+	} else if write {
+		for i := range w.reads {
+			if r := &w.reads[i]; r.epoch != 0 && !ctx.sees(r) {
+				prev, racy = *r, true
+				break
+			}
+		}
+	}
+	if write {
+		// An access that happens after this write happens after the
+		// reads it races with, or races with this write.
+		w.write = cur
+		w.reads = [race2WordReads]race2Access{}
+	} else {
+		w.addRead(ctx, &cur)
+	}
+	unlock(&b.lock)
+
+	if racy {
+		lock(&race2accesses.lock)
+		racy = race2markReported(cur.callerpc, prev.callerpc)
+		race2accesses.reports++
+		unlock(&race2accesses.lock)
+	}
+
+	if racy {
+		race2report(addr, &cur, &prev)
+	}
+}
+
+// addRead records the read cur by ctx in w, dropping the reads that
+// happen before it. The lock of w's bucket must be held.
+func (w *race2Word) addRead(ctx *race2ctx, cur *race2Access) {
+	n := 0
+	for i := range w.reads {
+		if r := &w.reads[i]; r.epoch != 0 && !ctx.sees(r) {
+			w.reads[n] = *r
+			n++
+		}
+	}
+	if n == len(w.reads) {
+		copy(w.reads[:], w.reads[1:])
+		n--
+	}
+	w.reads[n] = *cur
+	for n++; n < len(w.reads); n++ {
+		w.reads[n] = race2Access{}
+	}
+}
+
+// sees reports whether a happens before the current point of ctx.
+// Accesses made in ctx's own slot always do.
+func (ctx *race2ctx) sees(a *race2Access) bool {
+	return a.slot == ctx.slot || ctx.clock[a.slot] >= a.epoch
+}
+
+// race2lockWord returns the access record for word, creating it if
+// necessary, and its bucket, with the lock of the bucket held. The
+// caller must unlock it.
+func race2lockWord(word uintptr) (*race2Word, *race2WordBucket) {
+	b := &race2accesses.buckets[race2syncHash(word)]
+	lock(&b.lock)
+	for {
+		for w := b.words; w != nil; w = w.next {
+			if w.addr == word {
+				return w, b
+			}
+		}
+		lock(&race2accesses.lock)
+		w := race2accesses.free
+		if w != nil {
+			race2accesses.free = w.next
+		}
+		unlock(&race2accesses.lock)
+		if w != nil {
+			w.addr = word
+			w.write = race2Access{}
+			w.reads = [race2WordReads]race2Access{}
+			w.next = b.words
+			b.words = w
+			atomic.Or8((*uint8)(unsafe.Pointer(race2shadowAddr(word))), race2ShadowAccess)
+			return w, b
+		}
+		// Allocate without holding the leaf lock.
+		unlock(&b.lock)
+		w = (*race2Word)(persistentalloc(unsafe.Sizeof(race2Word{}), 8, &memstats.other_sys))
+		lock(&race2accesses.lock)
+		w.next = race2accesses.free
+		race2accesses.free = w
+		unlock(&race2accesses.lock)
+		lock(&b.lock)
+	}
+}
+
+// race2forget drops the access records of the words in [p, p+sz),
+// which is being allocated or freed.
+func race2forget(p, sz uintptr) {
+	if sz == 0 || !race2shadowMapped(p) {
+		return
+	}
+	for word := p &^ 7; word < p+sz; word += 8 {
+		s := (*uint8)(unsafe.Pointer(race2shadowAddr(word)))
+		if atomic.Load8(s)&race2ShadowAccess == 0 {
+			continue
+		}
+		b := &race2accesses.buckets[race2syncHash(word)]
+		lock(&b.lock)
+		var dropped *race2Word
+		for wp := &b.words; *wp != nil; wp = &(*wp).next {
+			if w := *wp; w.addr == word {
+				*wp = w.next
+				dropped = w
+				break
+			}
+		}
+		atomic.And8(s, ^uint8(race2ShadowAccess))
+		unlock(&b.lock)
+		if dropped != nil {
+			lock(&race2accesses.lock)
+			dropped.next = race2accesses.free
+			race2accesses.free = dropped
+			unlock(&race2accesses.lock)
+		}
+	}
+}
+
+// race2markReported records that the call sites a and b raced, and
+// reports whether they should be reported. race2accesses.lock must be
+// held.
+func race2markReported(a, b uintptr) bool {
+	if a > b {
+		a, b = b, a
+	}
+	r := &race2accesses
+	for i := 0; i < r.nreported; i++ {
+		if r.reported[i] == [2]uintptr{a, b} {
+			return false
+		}
+	}
+	if r.nreported < len(r.reported) {
+		r.reported[r.nreported] = [2]uintptr{a, b}
+		r.nreported++
+	}
+	return true
+}
+
+// race2report prints a report of a race between the access cur by the
+// current goroutine and the earlier access prev, both to the word
+// containing addr. The current goroutine's stack is printed in full.
+func race2report(addr unsafe.Pointer, cur, prev *race2Access) {
+	gp := getg()
+	pc, sp := sys.GetCallerPC(), sys.GetCallerSP()
+	systemstack(func() {
+		g0 := getg()
+		if race2reportBuf != nil {
+			g0.writebuf = *race2reportBuf
+		}
+		printlock()
+		print("==================\n")
+		print("WARNING: DATA RACE\n")
+		race2printAccess(false, addr, cur)
+		printAncestorTracebackFuncInfo(findfunc(cur.pc), cur.pc)
+		traceback(pc, sp, 0, gp)
+		print("\n")
+		race2printAccess(true, addr, prev)
+		printAncestorTracebackFuncInfo(findfunc(prev.pc), prev.pc)
+		race2printCaller(prev.callerpc)
+		print("==================\n")
+		printunlock()
+		if race2reportBuf != nil {
+			*race2reportBuf = g0.writebuf
+			g0.writebuf = nil
+		}
+	})
+	if race2exitOnError.Load() {
+		exit(66)
+	}
+}
+
+// race2printCaller prints the frames of the call site whose return PC
+// is pc, including the frames of calls inlined at it.
+func race2printCaller(pc uintptr) {
+	f := findfunc(pc)
+	if !f.valid() {
+		print("unknown pc ", hex(pc), "\n")
+		return
+	}
+	// pc is a return PC, which may belong to the next line or the
+	// next inlined call. Look up the call instruction instead, as
+	// tracebacks do.
+	tracepc := pc - 1
+	for u, uf := newInlineUnwinder(f, tracepc); uf.valid(); uf = u.next(uf) {
+		file, line := u.fileLine(uf)
+		printFuncName(u.srcFunc(uf).name())
+		print("(...)\n")
+		print("\t", file, ":", line)
+		if pc > f.entry() {
+			print(" +", hex(pc-f.entry()))
+		}
+		print("\n")
+	}
+}
+
+func race2printAccess(previous bool, addr unsafe.Pointer, a *race2Access) {
+	switch {
+	case previous && a.write:
+		print("Previous write")
+	case previous:
+		print("Previous read")
+	case a.write:
+		print("Write")
+	default:
+		print("Read")
+	}
+	print(" at ", addr, " by goroutine ", a.goid, ":\n")
+}
@@ -0,0 +1,264 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime_test
+
+import (
+	"bytes"
+	"fmt"
+	"internal/testenv"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// race2Map is a map type whose operations are not specialized, and so
+// are reported to race2 by package runtime.
+type race2Map map[[2]int]int
+
+// race2Delete deletes k from m in a new goroutine that does not
+// synchronize with the caller, and waits for it. See race2Go. It
+// returns the line of the delete.
+func race2Delete(t *testing.T, m race2Map, k int, release *bool) (line int) {
+	var sameSlot bool
+	now := runtime.Race2Now()
+	race2Go(func() {
+		sameSlot = runtime.Race2Now().SameSlot(now)
+		delete(m, race2Key(k, &line))
+	}, release)
+	if sameSlot {
+		t.Skip("goroutines share a clock slot")
+	}
+	return line
+}
+
+// race2Key returns the key k of a race2Map, and sets *line to the line
+// it is called from.
+func race2Key(k int, line *int) [2]int {
+	_, _, *line, _ = runtime.Caller(1)
+	return [2]int{k}
+}
+
+func TestRace2Report(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	m := race2Map{{1}: 1, {2}: 2}
+	var line int
+	races, report := runtime.Race2CaptureReports(func() {
+		line = race2Delete(t, m, 1, &release)
+		delete(m, [2]int{2})
+	})
+	if races != 1 {
+		t.Fatalf("found %d races, want 1; reports:\n%s", races, report)
+	}
+	for _, want := range []string{
+		"WARNING: DATA RACE\n",
+		"Write at ",
+		"Previous write at ",
+		"runtime.mapdelete(...)",
+		"runtime_test.TestRace2Report(",
+		"runtime_test.race2Delete.func1(",
+		"race2access_test.go:",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+	// The previous write is shown at the line of its call, not the
+	// line its return PC belongs to.
+	_, prev, _ := strings.Cut(report, "Previous write at ")
+	if want := fmt.Sprintf("race2access_test.go:%d ", line); !strings.Contains(prev, want) {
+		t.Errorf("previous write is not reported at %q:\n%s", want, report)
+	}
+}
+
+func TestRace2ReportOnce(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	m := race2Map{{1}: 1, {2}: 2, {3}: 3}
+	races, report := runtime.Race2CaptureReports(func() {
+		for k := range 3 {
+			race2Delete(t, m, k, &release)
+		}
+	})
+	if races != 2 {
+		t.Fatalf("found %d races, want 2; reports:\n%s", races, report)
+	}
+	if n := strings.Count(report, "WARNING: DATA RACE"); n != 1 {
+		t.Errorf("got %d reports, want 1:\n%s", n, report)
+	}
+}
+
+func TestRace2ConcurrentReads(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	m := race2Map{{1}: 1}
+	var line, sum int
+	var sameSlot bool
+	races, report := runtime.Race2CaptureReports(func() {
+		// The first read is not ordered before the write, but the
+		// second, more recent read is.
+		now := runtime.Race2Now()
+		var first runtime.Race2Epoch
+		race2Go(func() {
+			first = runtime.Race2Now()
+			sum += m[race2Key(1, &line)]
+		}, &release)
+		c := make(chan runtime.Race2Epoch)
+		go func() {
+			second := runtime.Race2Now()
+			sum += m[[2]int{1}]
+			c <- second
+		}()
+		second := <-c
+		sameSlot = first.SameSlot(now) || first.SameSlot(second)
+		delete(m, [2]int{1})
+	})
+	if sameSlot {
+		t.Skip("goroutines share a clock slot")
+	}
+	if races != 1 {
+		t.Fatalf("found %d races, want 1; reports:\n%s", races, report)
+	}
+	_, prev, _ := strings.Cut(report, "Previous read at ")
+	if want := fmt.Sprintf("race2access_test.go:%d ", line); !strings.Contains(prev, want) {
+		t.Errorf("previous read is not reported at %q:\n%s", want, report)
+	}
+}
+
+func TestRace2HaltOnError(t *testing.T) {
+	if os.Getenv("GO_TEST_RACE2_HALT") == "1" {
+		// The goroutine of race2Delete is never released: the
+		// program should exit at the race.
+		release := false
+		m := race2Map{{1}: 1, {2}: 2}
+		race2Delete(t, m, 1, &release)
+		delete(m, [2]int{2})
+		t.Fatal("program did not exit after the race")
+	}
+
+	cmd := testenv.CleanCmdEnv(exec.Command(testenv.Executable(t), "-test.run=^TestRace2HaltOnError$", "-test.v"))
+	cmd.Env = append(cmd.Env, "GO_TEST_RACE2_HALT=1", "GORACE=halt_on_error=1")
+	out, err := cmd.CombinedOutput()
+	t.Logf("%s", out)
+	if err == nil && bytes.Contains(out, []byte("goroutines share a clock slot")) {
+		t.Skip("goroutines share a clock slot")
+	}
+	if ee, ok := err.(*exec.ExitError); !ok || ee.ExitCode() != 66 {
+		t.Fatalf("child process: %v, want exit status 66", err)
+	}
+	if want := "WARNING: DATA RACE"; !bytes.Contains(out, []byte(want)) {
+		t.Errorf("output does not contain %q", want)
+	}
+}
+
+func TestRace2NoReport(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	m := race2Map{{1}: 1, {2}: 2}
+	races, report := runtime.Race2CaptureReports(func() {
+		// Channel operations order the deletes.
+		c := make(chan bool)
+		go func() {
+			delete(m, [2]int{1})
+			c <- true
+		}()
+		<-c
+		delete(m, [2]int{2})
+
+		// So does starting a goroutine.
+		done := make(chan bool, 1)
+		go func() {
+			delete(m, [2]int{3})
+			done <- true
+		}()
+		<-done
+
+		// So does a mutex.
+		var mu sync.Mutex
+		race2Go(func() {
+			mu.Lock()
+			delete(m, [2]int{4})
+			mu.Unlock()
+		}, &release)
+		mu.Lock()
+		delete(m, [2]int{5})
+		mu.Unlock()
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				mu.Lock()
+				clear(m)
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+
+		// So do atomic operations.
+		var stored atomic.Bool
+		race2Go(func() {
+			delete(m, [2]int{6})
+			stored.Store(true)
+		}, &release)
+		if stored.Load() {
+			delete(m, [2]int{7})
+		}
+		var n atomic.Int64
+		race2Go(func() {
+			delete(m, [2]int{8})
+			n.Add(1)
+		}, &release)
+		if n.Add(1) == 2 {
+			delete(m, [2]int{9})
+		}
+	})
+	if races != 0 {
+		t.Errorf("found %d races, want 0; reports:\n%s", races, report)
+	}
+}
+
+func TestRace2NoReportParallel(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// Goroutines running in parallel update maps under their own
+	// mutexes and a shared one, so that sync variables and access
+	// records are looked up and created concurrently.
+	shared := race2Map{}
+	var sharedMu sync.Mutex
+	races, report := runtime.Race2CaptureReports(func() {
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Go(func() {
+				m := race2Map{}
+				var mu sync.Mutex
+				for j := range 1000 {
+					mu.Lock()
+					m[[2]int{j % 16}]++
+					mu.Unlock()
+					if j%10 == 0 {
+						sharedMu.Lock()
+						shared[[2]int{i}]++
+						sharedMu.Unlock()
+					}
+				}
+			})
+		}
+		wg.Wait()
+		clear(shared)
+	})
+	if races != 0 {
+		t.Errorf("found %d races, want 0; reports:\n%s", races, report)
+	}
+}
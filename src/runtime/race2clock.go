@@ -0,0 +1,390 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime
+
+import (
+	"internal/runtime/atomic"
+	"internal/runtime/sys"
+	"unsafe"
+)
+
+// Happens-before tracking for the race2 detector.
+//
+// Every goroutine has a vector clock, kept in the race2ctx that its
+// racectx points to. A vector clock has one entry per slot. Each
+// goroutine is assigned a slot when it starts and advances its own
+// entry, its epoch, after every release, so an event of goroutine G at
+// epoch e happens before a point in goroutine H if H's clock has at
+// least e in G's slot.
+//
+// A release publishes the goroutine's clock to a sync variable keyed
+// by the address of the sync object, and an acquire merges the sync
+// variable's clock into the goroutine's clock. Sync variables live in
+// a hash table and are never freed; a sync object allocated at the
+// address of a dead one reuses its variable. Once a sync variable
+// exists, the race2ShadowSync bit is set in the shadow of its address,
+// if that shadow is mapped, so that acquires of objects that were never
+// released need not consult the table.
+//
+// Slots are recycled when goroutines exit, and if more than
+// race2ClockSlots goroutines are alive at once, some share a slot.
+// Either way, synchronizing with one user of a slot orders the events
+// of its other users up to the same epoch, which can hide races.
+//
+// Conversely, race2 only knows the happens-before edges that are
+// reported to it: by channels, goroutine creation and the other
+// annotated points in the runtime, and by packages sync and sync/atomic
+// through race2sync.go. Accesses ordered only by synchronization it
+// does not see, such as synchronization in C code, are reported as
+// races.
+//
+// Goroutines are the only contexts with clocks: the contexts of timers
+// and cleanups are 0, and operations on them are ignored.
+//
+// A goroutine's clock is only used by the goroutine itself, or by the
+// goroutine that completes a channel operation it is blocked in, which
+// the channel lock orders with it, so it needs no lock. Each sync
+// variable has its own lock, and the hash table of sync variables is
+// read without locking, since variables are never removed from it.
+// race2clocks.lock is only taken to create sync variables and to start
+// and end goroutines.
+
+const (
+	// race2ClockSlots is the number of entries in a vector clock.
+	race2ClockSlots = 64
+
+	// race2ShadowSync is set in the shadow byte of a sync object
+	// address when the object has a sync variable.
+	race2ShadowSync = 1 << 0
+
+	race2SyncBucketBits = 12
+)
+
+type race2Clock [race2ClockSlots]uint64
+
+// join sets c to the element-wise maximum of c and o.
+func (c *race2Clock) join(o *race2Clock) {
+	for i := range c {
+		c[i] = max(c[i], o[i])
+	}
+}
+
+// race2ctx is the race2 state of a goroutine.
+type race2ctx struct {
+	_     sys.NotInHeap
+	slot  uint32
+	clock race2Clock
+	next  *race2ctx // on race2clocks.freeCtx
+}
+
+// race2SyncVar is the vector clock of a sync object.
+type race2SyncVar struct {
+	_    sys.NotInHeap
+	addr uintptr
+
+	// lock is a leaf lock protecting clock.
+	lock  mutex
+	clock race2Clock
+
+	// next is the next variable in the hash bucket, which does not
+	// change once the variable is in the table, or on
+	// race2clocks.freeVar.
+	next *race2SyncVar
+}
+
+var race2clocks struct {
+	// lock is a leaf lock protecting the free lists and slotRefs
+	// and nextSlot, and serializing insertions into vars.
+	lock mutex
+
+	freeCtx *race2ctx
+	freeVar *race2SyncVar
+
+	// slotRefs counts the goroutines using each slot. epochs holds
+	// the latest epoch handed out in each slot, so that a recycled
+	// slot continues from where its previous owner stopped. Goroutines
+	// sharing a slot advance it concurrently, so it is atomic.
+	slotRefs [race2ClockSlots]uint32
+	epochs   [race2ClockSlots]atomic.Uint64
+	nextSlot uint32 // where the search for an unused slot starts
+
+	// vars are the hash buckets of sync variables. They are loaded
+	// and stored atomically.
+	vars [1 << race2SyncBucketBits]*race2SyncVar
+}
+
+// race2ctx0 is the context of the main goroutine.
+var race2ctx0 race2ctx
+
+func race2ctxOf(racectx uintptr) *race2ctx {
+	return (*race2ctx)(unsafe.Pointer(racectx))
+}
+
+// tick advances ctx's epoch.
+func (ctx *race2ctx) tick() {
+	ctx.clock[ctx.slot] = race2clocks.epochs[ctx.slot].Add(1)
+}
+
+// race2allocSlot returns a slot for a new goroutine, preferring an
+// unused one. race2clocks.lock must be held.
+func race2allocSlot() uint32 {
+	slot := race2clocks.nextSlot
+	for i := uint32(0); i < race2ClockSlots; i++ {
+		if s := (race2clocks.nextSlot + i) % race2ClockSlots; race2clocks.slotRefs[s] == 0 {
+			slot = s
+			break
+		}
+	}
+	race2clocks.slotRefs[slot]++
+	race2clocks.nextSlot = (slot + 1) % race2ClockSlots
+	return slot
+}
+
+// race2initctx0 sets up the context of the main goroutine.
+func race2initctx0() uintptr {
+	lock(&race2clocks.lock)
+	race2ctx0.slot = race2allocSlot()
+	race2ctx0.tick()
+	unlock(&race2clocks.lock)
+	return uintptr(unsafe.Pointer(&race2ctx0))
+}
+
+// race2gostart returns the context of a goroutine being created by
+// the current goroutine. Everything the creator did so far happens
+// before the new goroutine starts.
+//
+//go:nosplit
+func race2gostart(pc uintptr) uintptr {
+	gp := getg()
+	spawng := gp
+	if gp.m.curg != nil {
+		spawng = gp.m.curg
+	}
+
+	lock(&race2clocks.lock)
+	for race2clocks.freeCtx == nil {
+		// Allocate without holding the leaf lock.
+		unlock(&race2clocks.lock)
+		ctx := (*race2ctx)(persistentalloc(unsafe.Sizeof(race2ctx{}), 8, &memstats.other_sys))
+		lock(&race2clocks.lock)
+		ctx.next = race2clocks.freeCtx
+		race2clocks.freeCtx = ctx
+	}
+	ctx := race2clocks.freeCtx
+	race2clocks.freeCtx = ctx.next
+	ctx.next = nil
+	ctx.slot = race2allocSlot()
+	if parent := race2ctxOf(spawng.racectx); parent != nil {
+		ctx.clock = parent.clock
+		parent.tick()
+	} else {
+		ctx.clock = race2Clock{}
+	}
+	ctx.tick()
+	unlock(&race2clocks.lock)
+	return uintptr(unsafe.Pointer(ctx))
+}
+
+// race2goend releases the context of the exiting goroutine.
+//
+//go:nosplit
+func race2goend() {
+	gp := getg()
+	ctx := race2ctxOf(gp.racectx)
+	if ctx == nil {
+		return
+	}
+	gp.racectx = 0
+	lock(&race2clocks.lock)
+	race2clocks.slotRefs[ctx.slot]--
+	ctx.next = race2clocks.freeCtx
+	race2clocks.freeCtx = ctx
+	unlock(&race2clocks.lock)
+}
+
+func race2syncHash(addr uintptr) uintptr {
+	return uintptr(uint64(addr>>3) * 0x9e3779b97f4a7c15 >> (64 - race2SyncBucketBits))
+}
+
+// race2findSyncVar returns the sync variable for addr, or nil.
+// It may be called without holding race2clocks.lock.
+func race2findSyncVar(addr uintptr) *race2SyncVar {
+	b := &race2clocks.vars[race2syncHash(addr)]
+	for v := (*race2SyncVar)(atomic.Loadp(unsafe.Pointer(b))); v != nil; v = v.next {
+		if v.addr == addr {
+			return v
+		}
+	}
+	return nil
+}
+
+// race2lockSyncVar returns the sync variable for addr, creating it if
+// necessary, with its lock held. The caller must unlock it.
+func race2lockSyncVar(addr uintptr) *race2SyncVar {
+	if v := race2findSyncVar(addr); v != nil {
+		lock(&v.lock)
+		return v
+	}
+	lock(&race2clocks.lock)
+	for {
+		if v := race2findSyncVar(addr); v != nil {
+			unlock(&race2clocks.lock)
+			lock(&v.lock)
+			return v
+		}
+		if v := race2clocks.freeVar; v != nil {
+			race2clocks.freeVar = v.next
+			v.addr = addr
+			v.clock = race2Clock{}
+			b := &race2clocks.vars[race2syncHash(addr)]
+			v.next = *b
+			atomic.StorepNoWB(unsafe.Pointer(b), unsafe.Pointer(v))
+			if race2shadowMapped(addr) {
+				atomic.Or8((*uint8)(unsafe.Pointer(race2shadowAddr(addr))), race2ShadowSync)
+			}
+			unlock(&race2clocks.lock)
+			lock(&v.lock)
+			return v
+		}
+		// Allocate without holding the leaf lock.
+		unlock(&race2clocks.lock)
+		v := (*race2SyncVar)(persistentalloc(unsafe.Sizeof(race2SyncVar{}), 8, &memstats.other_sys))
+		lock(&race2clocks.lock)
+		v.next = race2clocks.freeVar
+		race2clocks.freeVar = v
+	}
+}
+
+// race2maybeSync reports whether addr may have a sync variable.
+func race2maybeSync(addr uintptr) bool {
+	if !race2shadowMapped(addr) {
+		return true
+	}
+	return atomic.Load8((*uint8)(unsafe.Pointer(race2shadowAddr(addr))))&race2ShadowSync != 0
+}
+
+//go:nosplit
+func race2acquire(addr unsafe.Pointer) {
+	race2acquireg(getg(), addr)
+}
+
+//go:nosplit
+func race2acquireg(gp *g, addr unsafe.Pointer) {
+	if getg().raceignore != 0 {
+		return
+	}
+	race2acquirectx(gp.racectx, addr)
+}
+
+//go:nosplit
+func race2acquirectx(racectx uintptr, addr unsafe.Pointer) {
+	ctx := race2ctxOf(racectx)
+	if ctx == nil || !race2maybeSync(uintptr(addr)) {
+		return
+	}
+	if v := race2findSyncVar(uintptr(addr)); v != nil {
+		lock(&v.lock)
+		ctx.clock.join(&v.clock)
+		unlock(&v.lock)
+	}
+}
+
+//go:nosplit
+func race2release(addr unsafe.Pointer) {
+	race2releaseg(getg(), addr)
+}
+
+// race2releaseg publishes gp's clock to addr, replacing the clock of
+// any earlier release.
+//
+//go:nosplit
+func race2releaseg(gp *g, addr unsafe.Pointer) {
+	ctx := race2ctxOf(gp.racectx)
+	if getg().raceignore != 0 || ctx == nil {
+		return
+	}
+	v := race2lockSyncVar(uintptr(addr))
+	v.clock = ctx.clock
+	unlock(&v.lock)
+	ctx.tick()
+}
+
+//go:nosplit
+func race2releaseacquire(addr unsafe.Pointer) {
+	race2releaseacquireg(getg(), addr)
+}
+
+// race2releaseacquireg acquires addr for gp and then releases it,
+// replacing the clock at addr.
+//
+//go:nosplit
+func race2releaseacquireg(gp *g, addr unsafe.Pointer) {
+	ctx := race2ctxOf(gp.racectx)
+	if getg().raceignore != 0 || ctx == nil {
+		return
+	}
+	v := race2lockSyncVar(uintptr(addr))
+	ctx.clock.join(&v.clock)
+	v.clock = ctx.clock
+	unlock(&v.lock)
+	ctx.tick()
+}
+
+//go:nosplit
+func race2releasemerge(addr unsafe.Pointer) {
+	race2releasemergeg(getg(), addr)
+}
+
+// race2releasemergeg merges gp's clock into the clock at addr, so that
+// an acquire of addr is ordered after every merged release.
+//
+//go:nosplit
+func race2releasemergeg(gp *g, addr unsafe.Pointer) {
+	ctx := race2ctxOf(gp.racectx)
+	if getg().raceignore != 0 || ctx == nil {
+		return
+	}
+	v := race2lockSyncVar(uintptr(addr))
+	v.clock.join(&ctx.clock)
+	unlock(&v.lock)
+	ctx.tick()
+}
+
+// race2sync orders a direct handoff between the current goroutine and
+// the goroutine of sg on channel c in both directions.
+//
+//go:nosplit
+func race2sync(c *hchan, sg *sudog) {
+	race2release(chanbuf(c, 0))
+	race2acquireg(sg.g, chanbuf(c, 0))
+	race2releaseg(sg.g, chanbuf(c, 0))
+	race2acquire(chanbuf(c, 0))
+}
+
+// race2notify records a send or receive involving buffer entry idx of
+// channel c, by the current goroutine or, if sg is not nil, by the
+// goroutine of sg. It follows racenotify.
+//
+//go:nosplit
+func race2notify(c *hchan, idx uint, sg *sudog) {
+	qp := chanbuf(c, idx)
+	if c.elemsize == 0 {
+		if sg == nil {
+			race2acquire(qp)
+			race2release(qp)
+		} else {
+			race2acquireg(sg.g, qp)
+			race2releaseg(sg.g, qp)
+		}
+	} else {
+		if sg == nil {
+			race2releaseacquire(qp)
+		} else {
+			race2releaseacquireg(sg.g, qp)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+// race2Go runs f in a new goroutine and waits for it to return,
+// without synchronizing with it. The goroutine does not exit until
+// *release is set, so that its clock slot is not recycled while the
+// test inspects it.
+//
+// The goroutines spin on plain variables: waiting through a channel,
+// a mutex or even an atomic variable is a happens-before edge, which
+// race2 sees.
+func race2Go(f func(), release *bool) {
+	done := false
+	go func() {
+		f()
+		done = true
+		for !*release {
+			runtime.Gosched()
+		}
+	}()
+	for !done {
+		runtime.Gosched()
+	}
+}
+
+func TestRace2GoStart(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	start := runtime.Race2Now()
+	var sawStart bool
+	var child runtime.Race2Epoch
+	race2Go(func() {
+		sawStart = runtime.Race2Sees(start)
+		child = runtime.Race2Now()
+	}, &release)
+
+	if !sawStart {
+		t.Errorf("goroutine creation is not ordered before the new goroutine")
+	}
+	if child.SameSlot(start) {
+		t.Skip("goroutines share a clock slot")
+	}
+	if runtime.Race2Sees(child) {
+		t.Errorf("new goroutine is ordered before its creator without synchronization")
+	}
+}
+
+func TestRace2AcquireRelease(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	var mu uint64
+	var before, after runtime.Race2Epoch
+	race2Go(func() {
+		before = runtime.Race2Now()
+		runtime.Race2Release(unsafe.Pointer(&mu))
+		after = runtime.Race2Now()
+	}, &release)
+
+	if before.SameSlot(runtime.Race2Now()) {
+		t.Skip("goroutines share a clock slot")
+	}
+	if runtime.Race2Sees(before) {
+		t.Fatalf("release is ordered before acquire happened")
+	}
+	runtime.Race2Acquire(unsafe.Pointer(&mu))
+	if !runtime.Race2Sees(before) {
+		t.Errorf("events before the release are not ordered before the acquire")
+	}
+	if runtime.Race2Sees(after) {
+		t.Errorf("events after the release are ordered before the acquire")
+	}
+}
+
+func TestRace2ReleaseMerge(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	var mu, mergeMu uint64
+	var a, b runtime.Race2Epoch
+	race2Go(func() {
+		a = runtime.Race2Now()
+		runtime.Race2Release(unsafe.Pointer(&mu))
+		runtime.Race2ReleaseMerge(unsafe.Pointer(&mergeMu))
+	}, &release)
+	race2Go(func() {
+		b = runtime.Race2Now()
+		runtime.Race2Release(unsafe.Pointer(&mu))
+		runtime.Race2ReleaseMerge(unsafe.Pointer(&mergeMu))
+	}, &release)
+	if a.SameSlot(b) || a.SameSlot(runtime.Race2Now()) || b.SameSlot(runtime.Race2Now()) {
+		t.Skip("goroutines share a clock slot")
+	}
+
+	// A merging release keeps the clocks of earlier releases.
+	runtime.Race2Acquire(unsafe.Pointer(&mergeMu))
+	if !runtime.Race2Sees(a) || !runtime.Race2Sees(b) {
+		t.Errorf("acquire after merging releases: saw first %v, second %v, want both", runtime.Race2Sees(a), runtime.Race2Sees(b))
+	}
+}
+
+func TestRace2Release(t *testing.T) {
+	release := false
+	defer func() { release = true }()
+
+	var mu uint64
+	var a, b runtime.Race2Epoch
+	race2Go(func() {
+		a = runtime.Race2Now()
+		runtime.Race2Release(unsafe.Pointer(&mu))
+	}, &release)
+	race2Go(func() {
+		b = runtime.Race2Now()
+		runtime.Race2Release(unsafe.Pointer(&mu))
+	}, &release)
+	if a.SameSlot(b) || a.SameSlot(runtime.Race2Now()) || b.SameSlot(runtime.Race2Now()) {
+		t.Skip("goroutines share a clock slot")
+	}
+
+	// A plain release replaces the clock of the earlier, unordered one.
+	runtime.Race2Acquire(unsafe.Pointer(&mu))
+	if runtime.Race2Sees(a) || !runtime.Race2Sees(b) {
+		t.Errorf("acquire after releases: saw first %v, second %v, want only second", runtime.Race2Sees(a), runtime.Race2Sees(b))
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime
+
+import (
+	"internal/runtime/atomic"
+	"unsafe"
+)
+
+// Shadow memory for the race2 detector.
+//
+// Every 1<<race2ShadowScale bytes of application memory are described
+// by one byte of shadow memory at
+//
+//	race2shadow.base + addr>>race2ShadowScale
+//
+// race2init reserves address space for the shadow of the whole heap
+// address range, but nothing is backed by memory until race2mapshadow
+// is called for the application range it describes. Shadow is mapped
+// one chunk at a time, where a chunk describes heapArenaBytes of
+// application memory, and a bitmap records which chunks are mapped so
+// that mapping a range again does not clear its shadow.
+//
+// Like the shadow of the race detector, this memory is not accounted
+// for in memstats.
+
+const (
+	// race2ShadowScale is log2 of the number of application bytes
+	// described by one shadow byte.
+	race2ShadowScale = 3
+
+	// race2ShadowBytes is the size of the shadow reservation.
+	race2ShadowBytes = 1 << (heapAddrBits - race2ShadowScale)
+
+	// race2ShadowChunks is the number of shadow chunks.
+	race2ShadowChunks = 1 << (heapAddrBits - logHeapArenaBytes)
+)
+
+var race2shadow struct {
+	// lock is a leaf lock serializing updates of mapped.
+	lock mutex
+
+	// base is the start of the shadow reservation.
+	base uintptr
+
+	// mapped has bit i set if chunk i of the shadow is mapped.
+	// Bits are set atomically, so they may be read without the lock.
+	mapped *[race2ShadowChunks / 8]uint8
+}
+
+// race2shadowAddr returns the address of the shadow of addr.
+//
+//go:nosplit
+func race2shadowAddr(addr uintptr) uintptr {
+	return race2shadow.base + addr>>race2ShadowScale
+}
+
+func race2init() (gctx, pctx uintptr) {
+	p := sysReserve(nil, race2ShadowBytes, "race2 shadow")
+	if p == nil {
+		throw("race2: cannot reserve shadow memory")
+	}
+	race2shadow.base = uintptr(p)
+	race2shadow.mapped = (*[race2ShadowChunks / 8]uint8)(sysAllocOS(race2ShadowChunks/8, "race2 shadow bitmap"))
+	if race2shadow.mapped == nil {
+		throw("race2: out of memory")
+	}
+
+	// Map the shadow of the data and bss segments. The heap is
+	// mapped as it grows, by sysAlloc.
+	start := min(firstmoduledata.noptrdata, firstmoduledata.data, firstmoduledata.noptrbss, firstmoduledata.bss)
+	end := max(firstmoduledata.enoptrdata, firstmoduledata.edata, firstmoduledata.enoptrbss, firstmoduledata.ebss)
+	race2mapshadow(unsafe.Pointer(start), end-start)
+	return race2initctx0(), 0
+}
+
+// race2mapshadow maps the shadow of the application memory
+// [addr, addr+size). Shadow that is already mapped is left untouched,
+// so it is safe to call race2mapshadow on overlapping ranges.
+// Newly mapped shadow is zero.
+func race2mapshadow(addr unsafe.Pointer, size uintptr) {
+	if size == 0 {
+		return
+	}
+	start, end := uintptr(addr), uintptr(addr)+size
+	if end < start || (end-1)>>heapAddrBits != 0 {
+		print("race2: mapshadow(", addr, ", ", size, ") outside of the heap address range\n")
+		throw("race2: bad shadow range")
+	}
+
+	lock(&race2shadow.lock)
+	for c := start / heapArenaBytes; c <= (end-1)/heapArenaBytes; c++ {
+		if race2shadow.mapped[c/8]&(1<<(c%8)) != 0 {
+			continue
+		}
+		v := unsafe.Pointer(race2shadowAddr(c * heapArenaBytes))
+		sysMapOS(v, heapArenaBytes>>race2ShadowScale, "race2 shadow")
+		sysUsedOS(v, heapArenaBytes>>race2ShadowScale)
+		atomic.Or8(&race2shadow.mapped[c/8], 1<<(c%8))
+	}
+	unlock(&race2shadow.lock)
+}
+
+// race2shadowMapped reports whether the shadow of addr is mapped.
+// It may be called without holding race2shadow.lock.
+func race2shadowMapped(addr uintptr) bool {
+	if addr>>heapAddrBits != 0 {
+		return false
+	}
+	c := addr / heapArenaBytes
+	return atomic.Load8(&race2shadow.mapped[c/8])&(1<<(c%8)) != 0
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+var race2HeapSink *[1 << 10]byte
+
+func TestRace2MapShadow(t *testing.T) {
+	// Use a range well away from the heap, whose shadow is
+	// not mapped yet.
+	const chunk = runtime.Race2ShadowChunkBytes
+	addr := uintptr(3 * chunk)
+	size := uintptr(2 * chunk)
+
+	runtime.Race2MapShadow(addr, size)
+	shadow := runtime.Race2Shadow(addr, size)
+	for i, b := range shadow {
+		if b != 0 {
+			t.Fatalf("shadow byte %d of new mapping is %#x, want 0", i, b)
+		}
+	}
+
+	// Mapping an overlapping range again must not clear the shadow.
+	shadow[0] = 1
+	shadow[len(shadow)-1] = 2
+	runtime.Race2MapShadow(addr+chunk/2, size)
+	if shadow[0] != 1 || shadow[len(shadow)-1] != 2 {
+		t.Errorf("remapping cleared the shadow: got %#x, %#x, want 0x1, 0x2", shadow[0], shadow[len(shadow)-1])
+	}
+	more := runtime.Race2Shadow(addr+size, chunk/2)
+	for i, b := range more {
+		if b != 0 {
+			t.Fatalf("shadow byte %d of extended mapping is %#x, want 0", i, b)
+		}
+	}
+	shadow[0] = 0
+	shadow[len(shadow)-1] = 0
+}
+
+func TestRace2HeapShadow(t *testing.T) {
+	// The heap's shadow is mapped as the heap grows.
+	race2HeapSink = new([1 << 10]byte)
+	p := uintptr(unsafe.Pointer(race2HeapSink))
+	for i, b := range runtime.Race2Shadow(p, unsafe.Sizeof(*race2HeapSink)) {
+		if b != 0 {
+			t.Fatalf("heap shadow byte %d is %#x, want 0", i, b)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race2
+
+package runtime
+
+import (
+	"internal/abi"
+	"internal/runtime/atomic"
+	"unsafe"
+)
+
+// Synchronization outside of package runtime, for the race2 detector.
+//
+// Package internal/race is enabled with race2, so package sync reports
+// its acquire and release points through the functions it declares,
+// as it does for the race detector. Those functions are pushed from
+// here.
+//
+// The operations of package sync/atomic are implemented here as well.
+// Every atomic operation on an address releases the calling goroutine's
+// clock to it before the operation and, if the operation reads the
+// address, acquires the address's clock after it. Releases merge into
+// the clock of the address, so an operation is ordered after every
+// earlier operation on the same address, as the Go memory model
+// requires of sequentially consistent atomics. The compiler does not
+// make sync/atomic intrinsic in race2 builds, so all calls reach these
+// functions.
+
+//go:linkname race2_Read internal/race.Read
+func race2_Read(addr unsafe.Pointer) {
+	race2read(addr)
+}
+
+//go:linkname race2_Write internal/race.Write
+func race2_Write(addr unsafe.Pointer) {
+	race2write(addr)
+}
+
+//go:linkname race2_ReadRange internal/race.ReadRange
+func race2_ReadRange(addr unsafe.Pointer, len int) {
+	race2readrange(addr, uintptr(len))
+}
+
+//go:linkname race2_WriteRange internal/race.WriteRange
+func race2_WriteRange(addr unsafe.Pointer, len int) {
+	race2writerange(addr, uintptr(len))
+}
+
+//go:linkname race2_ReadPC internal/race.ReadPC
+func race2_ReadPC(addr unsafe.Pointer, callerpc, pc uintptr) {
+	race2readpc(addr, callerpc, pc)
+}
+
+//go:linkname race2_WritePC internal/race.WritePC
+func race2_WritePC(addr unsafe.Pointer, callerpc, pc uintptr) {
+	race2writepc(addr, callerpc, pc)
+}
+
+//go:linkname race2_ReadObjectPC internal/race.ReadObjectPC
+func race2_ReadObjectPC(t *abi.Type, addr unsafe.Pointer, callerpc, pc uintptr) {
+	race2ReadObjectPC(t, addr, callerpc, pc)
+}
+
+//go:linkname race2_WriteObjectPC internal/race.WriteObjectPC
+func race2_WriteObjectPC(t *abi.Type, addr unsafe.Pointer, callerpc, pc uintptr) {
+	race2WriteObjectPC(t, addr, callerpc, pc)
+}
+
+//go:linkname race2_Errors internal/race.Errors
+func race2_Errors() int {
+	lock(&race2accesses.lock)
+	n := race2accesses.reports
+	unlock(&race2accesses.lock)
+	return n
+}
+
+//go:linkname race2_Acquire internal/race.Acquire
+func race2_Acquire(addr unsafe.Pointer) {
+	race2acquire(addr)
+}
+
+//go:linkname race2_Release internal/race.Release
+func race2_Release(addr unsafe.Pointer) {
+	race2release(addr)
+}
+
+//go:linkname race2_ReleaseMerge internal/race.ReleaseMerge
+func race2_ReleaseMerge(addr unsafe.Pointer) {
+	race2releasemerge(addr)
+}
+
+// race2_Disable makes race2 ignore the synchronization events of the
+// current goroutine until the matching race2_Enable.
+//
+//go:linkname race2_Disable internal/race.Disable
+//go:nosplit
+func race2_Disable() {
+	getg().raceignore++
+}
+
+//go:linkname race2_Enable internal/race.Enable
+//go:nosplit
+func race2_Enable() {
+	getg().raceignore--
+}
+
+//go:linkname race2atomic_LoadInt32 sync/atomic.LoadInt32
+func race2atomic_LoadInt32(addr *int32) int32 {
+	v := int32(atomic.Load((*uint32)(unsafe.Pointer(addr))))
+	race2acquire(unsafe.Pointer(addr))
+	return v
+}
+
+//go:linkname race2atomic_StoreInt32 sync/atomic.StoreInt32
+func race2atomic_StoreInt32(addr *int32, val int32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	atomic.Store((*uint32)(unsafe.Pointer(addr)), uint32(val))
+}
+
+//go:linkname race2atomic_SwapInt32 sync/atomic.SwapInt32
+func race2atomic_SwapInt32(addr *int32, new int32) (old int32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = int32(atomic.Xchg((*uint32)(unsafe.Pointer(addr)), uint32(new)))
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_CompareAndSwapInt32 sync/atomic.CompareAndSwapInt32
+func race2atomic_CompareAndSwapInt32(addr *int32, old, new int32) (swapped bool) {
+	race2releasemerge(unsafe.Pointer(addr))
+	swapped = atomic.Cas((*uint32)(unsafe.Pointer(addr)), uint32(old), uint32(new))
+	race2acquire(unsafe.Pointer(addr))
+	return swapped
+}
+
+//go:linkname race2atomic_AddInt32 sync/atomic.AddInt32
+func race2atomic_AddInt32(addr *int32, delta int32) (new int32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	new = int32(atomic.Xadd((*uint32)(unsafe.Pointer(addr)), delta))
+	race2acquire(unsafe.Pointer(addr))
+	return new
+}
+
+//go:linkname race2atomic_AndInt32 sync/atomic.AndInt32
+func race2atomic_AndInt32(addr *int32, mask int32) (old int32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = int32(atomic.And32((*uint32)(unsafe.Pointer(addr)), uint32(mask)))
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_OrInt32 sync/atomic.OrInt32
+func race2atomic_OrInt32(addr *int32, mask int32) (old int32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = int32(atomic.Or32((*uint32)(unsafe.Pointer(addr)), uint32(mask)))
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_LoadUint32 sync/atomic.LoadUint32
+func race2atomic_LoadUint32(addr *uint32) uint32 {
+	v := atomic.Load(addr)
+	race2acquire(unsafe.Pointer(addr))
+	return v
+}
+
+//go:linkname race2atomic_StoreUint32 sync/atomic.StoreUint32
+func race2atomic_StoreUint32(addr *uint32, val uint32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	atomic.Store(addr, val)
+}
+
+//go:linkname race2atomic_SwapUint32 sync/atomic.SwapUint32
+func race2atomic_SwapUint32(addr *uint32, new uint32) (old uint32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Xchg(addr, new)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_CompareAndSwapUint32 sync/atomic.CompareAndSwapUint32
+func race2atomic_CompareAndSwapUint32(addr *uint32, old, new uint32) (swapped bool) {
+	race2releasemerge(unsafe.Pointer(addr))
+	swapped = atomic.Cas(addr, old, new)
+	race2acquire(unsafe.Pointer(addr))
+	return swapped
+}
+
+//go:linkname race2atomic_AddUint32 sync/atomic.AddUint32
+func race2atomic_AddUint32(addr *uint32, delta uint32) (new uint32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	new = atomic.Xadd(addr, int32(delta))
+	race2acquire(unsafe.Pointer(addr))
+	return new
+}
+
+//go:linkname race2atomic_AndUint32 sync/atomic.AndUint32
+func race2atomic_AndUint32(addr *uint32, mask uint32) (old uint32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.And32(addr, mask)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_OrUint32 sync/atomic.OrUint32
+func race2atomic_OrUint32(addr *uint32, mask uint32) (old uint32) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Or32(addr, mask)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_LoadInt64 sync/atomic.LoadInt64
+func race2atomic_LoadInt64(addr *int64) int64 {
+	v := int64(atomic.Load64((*uint64)(unsafe.Pointer(addr))))
+	race2acquire(unsafe.Pointer(addr))
+	return v
+}
+
+//go:linkname race2atomic_StoreInt64 sync/atomic.StoreInt64
+func race2atomic_StoreInt64(addr *int64, val int64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	atomic.Store64((*uint64)(unsafe.Pointer(addr)), uint64(val))
+}
+
+//go:linkname race2atomic_SwapInt64 sync/atomic.SwapInt64
+func race2atomic_SwapInt64(addr *int64, new int64) (old int64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = int64(atomic.Xchg64((*uint64)(unsafe.Pointer(addr)), uint64(new)))
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_CompareAndSwapInt64 sync/atomic.CompareAndSwapInt64
+func race2atomic_CompareAndSwapInt64(addr *int64, old, new int64) (swapped bool) {
+	race2releasemerge(unsafe.Pointer(addr))
+	swapped = atomic.Cas64((*uint64)(unsafe.Pointer(addr)), uint64(old), uint64(new))
+	race2acquire(unsafe.Pointer(addr))
+	return swapped
+}
+
+//go:linkname race2atomic_AddInt64 sync/atomic.AddInt64
+func race2atomic_AddInt64(addr *int64, delta int64) (new int64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	new = int64(atomic.Xadd64((*uint64)(unsafe.Pointer(addr)), delta))
+	race2acquire(unsafe.Pointer(addr))
+	return new
+}
+
+//go:linkname race2atomic_AndInt64 sync/atomic.AndInt64
+func race2atomic_AndInt64(addr *int64, mask int64) (old int64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = int64(atomic.And64((*uint64)(unsafe.Pointer(addr)), uint64(mask)))
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_OrInt64 sync/atomic.OrInt64
+func race2atomic_OrInt64(addr *int64, mask int64) (old int64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = int64(atomic.Or64((*uint64)(unsafe.Pointer(addr)), uint64(mask)))
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_LoadUint64 sync/atomic.LoadUint64
+func race2atomic_LoadUint64(addr *uint64) uint64 {
+	v := atomic.Load64(addr)
+	race2acquire(unsafe.Pointer(addr))
+	return v
+}
+
+//go:linkname race2atomic_StoreUint64 sync/atomic.StoreUint64
+func race2atomic_StoreUint64(addr *uint64, val uint64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	atomic.Store64(addr, val)
+}
+
+//go:linkname race2atomic_SwapUint64 sync/atomic.SwapUint64
+func race2atomic_SwapUint64(addr *uint64, new uint64) (old uint64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Xchg64(addr, new)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_CompareAndSwapUint64 sync/atomic.CompareAndSwapUint64
+func race2atomic_CompareAndSwapUint64(addr *uint64, old, new uint64) (swapped bool) {
+	race2releasemerge(unsafe.Pointer(addr))
+	swapped = atomic.Cas64(addr, old, new)
+	race2acquire(unsafe.Pointer(addr))
+	return swapped
+}
+
+//go:linkname race2atomic_AddUint64 sync/atomic.AddUint64
+func race2atomic_AddUint64(addr *uint64, delta uint64) (new uint64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	new = atomic.Xadd64(addr, int64(delta))
+	race2acquire(unsafe.Pointer(addr))
+	return new
+}
+
+//go:linkname race2atomic_AndUint64 sync/atomic.AndUint64
+func race2atomic_AndUint64(addr *uint64, mask uint64) (old uint64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.And64(addr, mask)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_OrUint64 sync/atomic.OrUint64
+func race2atomic_OrUint64(addr *uint64, mask uint64) (old uint64) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Or64(addr, mask)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_LoadUintptr sync/atomic.LoadUintptr
+func race2atomic_LoadUintptr(addr *uintptr) uintptr {
+	v := atomic.Loaduintptr(addr)
+	race2acquire(unsafe.Pointer(addr))
+	return v
+}
+
+//go:linkname race2atomic_StoreUintptr sync/atomic.StoreUintptr
+func race2atomic_StoreUintptr(addr *uintptr, val uintptr) {
+	race2releasemerge(unsafe.Pointer(addr))
+	atomic.Storeuintptr(addr, val)
+}
+
+//go:linkname race2atomic_SwapUintptr sync/atomic.SwapUintptr
+func race2atomic_SwapUintptr(addr *uintptr, new uintptr) (old uintptr) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Xchguintptr(addr, new)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_CompareAndSwapUintptr sync/atomic.CompareAndSwapUintptr
+func race2atomic_CompareAndSwapUintptr(addr *uintptr, old, new uintptr) (swapped bool) {
+	race2releasemerge(unsafe.Pointer(addr))
+	swapped = atomic.Casuintptr(addr, old, new)
+	race2acquire(unsafe.Pointer(addr))
+	return swapped
+}
+
+//go:linkname race2atomic_AddUintptr sync/atomic.AddUintptr
+func race2atomic_AddUintptr(addr *uintptr, delta uintptr) (new uintptr) {
+	race2releasemerge(unsafe.Pointer(addr))
+	new = atomic.Xadduintptr(addr, delta)
+	race2acquire(unsafe.Pointer(addr))
+	return new
+}
+
+//go:linkname race2atomic_AndUintptr sync/atomic.AndUintptr
+func race2atomic_AndUintptr(addr *uintptr, mask uintptr) (old uintptr) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Anduintptr(addr, mask)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_OrUintptr sync/atomic.OrUintptr
+func race2atomic_OrUintptr(addr *uintptr, mask uintptr) (old uintptr) {
+	race2releasemerge(unsafe.Pointer(addr))
+	old = atomic.Oruintptr(addr, mask)
+	race2acquire(unsafe.Pointer(addr))
+	return old
+}
+
+//go:linkname race2atomic_LoadPointer sync/atomic.LoadPointer
+func race2atomic_LoadPointer(addr *unsafe.Pointer) unsafe.Pointer {
+	v := atomic.Loadp(unsafe.Pointer(addr))
+	race2acquire(unsafe.Pointer(addr))
+	return v
+}
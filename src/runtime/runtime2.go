@@ -571,6 +571,10 @@ type g struct {
 	coroarg *coro // argument during coroutine transfers
 	bubble  *synctestBubble
 
+	// schedStats is empty unless the runtime is built with the
+	// schedstats build tag.
+	schedStats gSchedStats
+
 	// xRegs stores the extended register state if this G has been
 	// asynchronously preempted.
 	xRegs xRegPerG
@@ -598,6 +602,35 @@ type g struct {
 // latency tracking runs.
 const gTrackingPeriod = 8
 
+// pHistory is a ring of the IDs of the most recent distinct Ps a G ran
+// on. Consecutive runs on the same P are recorded once.
+type pHistory struct {
+	ids  [8]int32
+	next uint8 // index in ids of the next ID to record
+	n    uint8 // number of IDs recorded, up to len(ids)
+}
+
+// record notes that the G is about to run on P id.
+func (h *pHistory) record(id int32) {
+	if h.n > 0 && h.ids[(int(h.next)+len(h.ids)-1)%len(h.ids)] == id {
+		return
+	}
+	h.ids[h.next] = id
+	h.next = uint8((int(h.next) + 1) % len(h.ids))
+	if int(h.n) < len(h.ids) {
+		h.n++
+	}
+}
+
+// list returns the recorded P IDs, oldest first.
+func (h *pHistory) list() []int {
+	l := make([]int, 0, h.n)
+	for i := int(h.next) + len(h.ids) - int(h.n); len(l) < int(h.n); i++ {
+		l = append(l, int(h.ids[i%len(h.ids)]))
+	}
+	return l
+}
+
 const (
 	// tlsSlots is the number of pointer-sized slots reserved for TLS on some platforms,
 	// like Windows.
@@ -766,6 +799,10 @@ type p struct {
 	// only the owner P can CAS it to a valid G.
 	runnext guintptr
 
+	// runnextStreak is the number of consecutive Gs runqget took
+	// from runnext. See runnextStreakLimit.
+	runnextStreak uint32
+
 	// Available G's (status == Gdead)
 	gFree gList
 
@@ -855,6 +892,30 @@ type p struct {
 	// goroutinesCreated is the total count of goroutines created by this P.
 	goroutinesCreated uint64
 
+	// nvcsw and nivcsw count the goroutines descheduled on this P,
+	// split into voluntary (blocked or yielded) and involuntary
+	// (preempted) switches, like ru_nvcsw and ru_nivcsw in getrusage.
+	nvcsw  uint64
+	nivcsw uint64
+
+	// asyncPreempts counts the goroutines asynchronously preempted
+	// on this P.
+	asyncPreempts uint64
+
+	// stealCalls, stealHits and stealMisses count the calls to
+	// stealWork on this P, as described for the fields of schedt.
+	stealCalls  uint64
+	stealHits   uint64
+	stealMisses uint64
+
+	// stealsFrom counts the successful steals by this P from each P,
+	// indexed by victim P ID. It is reset when GOMAXPROCS changes,
+	// which increments sched.stealsFromGen.
+	stealsFrom []uint64
+
+	// schedCounts counts scheduling events on this P.
+	schedCounts schedCounts
+
 	// xRegs is the per-P extended register state used by asynchronous
 	// preemption. This is an empty struct on platforms that don't use extended
 	// register state.
@@ -987,6 +1048,52 @@ type schedt struct {
 	// goroutinesCreated (plus the value of goroutinesCreated on each P in allp)
 	// is the sum of all goroutines created by the program.
 	goroutinesCreated atomic.Uint64
+
+	// nvcsw and nivcsw (plus the values of nvcsw and nivcsw on each P in
+	// allp) are the total voluntary and involuntary goroutine context
+	// switches performed by the program.
+	nvcsw  atomic.Uint64
+	nivcsw atomic.Uint64
+
+	// asyncPreempts (plus the value of asyncPreempts on each P in allp)
+	// is the total number of asynchronous preemptions.
+	asyncPreempts atomic.Uint64
+
+	// stealCalls (plus the value of stealCalls on each P in allp)
+	// counts the calls to stealWork. stealHits and stealMisses count,
+	// likewise, the calls that stole a goroutine from another P and
+	// the calls that searched every P and came back empty-handed.
+	// Calls cut short by a pending GC or by a timer readying local work
+	// count as neither.
+	stealCalls  atomic.Uint64
+	stealHits   atomic.Uint64
+	stealMisses atomic.Uint64
+
+	// stealsFromGen is incremented each time procresize resets the
+	// stealsFrom counts of the Ps. It is protected by lock.
+	stealsFromGen uint64
+
+	// schedCounts (plus the value of schedCounts on each P in allp)
+	// counts the scheduling events of the program. It is protected
+	// by lock.
+	schedCounts schedCounts
+}
+
+// schedCounts counts scheduling events, for SchedulerMetrics.
+type schedCounts struct {
+	globalChecks   uint64 // lock-protected visits to the global run queue
+	localDequeues  uint64 // goroutines taken from the local run queue
+	globalDequeues uint64 // goroutines taken from the global run queue
+	steals         uint64 // successful steals from another P
+	parks          uint64 // goroutines parked
+}
+
+func (c *schedCounts) add(o *schedCounts) {
+	c.globalChecks += o.globalChecks
+	c.localDequeues += o.localDequeues
+	c.globalDequeues += o.globalDequeues
+	c.steals += o.steals
+	c.parks += o.parks
 }
 
 // Values for the flags field of a sigTabT.
@@ -1204,6 +1311,7 @@ const (
 	waitReasonSynctestSelect                          // "select (durable)"
 	waitReasonSynctestWaitGroupWait                   // "sync.WaitGroup.Wait (durable)"
 	waitReasonCleanupWait                             // "cleanup wait"
+	waitReasonScheduleObserverWait                    // "schedule observer wait"
 )
 
 var waitReasonStrings = [...]string{
@@ -1254,6 +1362,7 @@ var waitReasonStrings = [...]string{
 	waitReasonSynctestSelect:        "select (durable)",
 	waitReasonSynctestWaitGroupWait: "sync.WaitGroup.Wait (durable)",
 	waitReasonCleanupWait:           "cleanup wait",
+	waitReasonScheduleObserverWait:  "schedule observer wait",
 }
 
 func (w waitReason) String() string {
@@ -0,0 +1,481 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"internal/runtime/atomic"
+	"unsafe"
+)
+
+// The scheduling decision log records where each P found the goroutines
+// it ran, for debugging the scheduler in tests. It is disabled unless a
+// test enables it, and then costs findRunnable one atomic load per
+// decision.
+//
+// Each P logs to its own ring, written only by the owner of the P, so
+// logging takes no locks. The rings are merged by time when read.
+//
+// The log is also the source of the events delivered to schedule
+// observers. While any are registered, the log stays enabled, and a
+// goroutine collects the new decisions and passes them to the
+// observers. The goroutine parks until a P logs a decision, then gives
+// the Ps schedObserverPeriod to log more, so that busy Ps wake it once
+// per period. A P whose ring fills up faster wakes it early.
+
+// schedSource is where the scheduler found a goroutine to run.
+type schedSource uint8
+
+const (
+	schedSourceLocal  schedSource = iota + 1 // the P's local run queue
+	schedSourceGlobal                        // the global run queue
+	schedSourceSteal                         // another P's local run queue
+	schedSourceIdle                          // nowhere; the P went idle
+)
+
+func (s schedSource) String() string {
+	switch s {
+	case schedSourceLocal:
+		return "local"
+	case schedSourceGlobal:
+		return "global"
+	case schedSourceSteal:
+		return "steal"
+	case schedSourceIdle:
+		return "idle"
+	}
+	return "unknown"
+}
+
+// schedDecision is an entry in the decision log.
+type schedDecision struct {
+	when   int64  // nanotime
+	goid   uint64 // 0 for schedSourceIdle
+	pid    int32
+	source schedSource
+}
+
+// schedDecisionRing holds the latest decisions of one P.
+type schedDecisionRing struct {
+	n         atomic.Uint64 // number of decisions ever logged
+	delivered atomic.Uint64 // number of decisions passed to schedule observers
+	buf       [1024]schedDecision
+}
+
+var schedDecisionLog struct {
+	enabled atomic.Bool
+
+	// forTest is set while a test has enabled the log. It is changed
+	// with the world stopped.
+	forTest bool
+
+	// rings holds a ring for each P, indexed by P ID.
+	rings atomic.Pointer[[]schedDecisionRing]
+}
+
+// logSchedDecision records that pp found gp in src, or, if src is
+// schedSourceIdle, that pp found nothing to run. It must be called by
+// the owner of pp.
+func (pp *p) logSchedDecision(src schedSource, gp *g) {
+	if !schedDecisionLog.enabled.Load() {
+		return
+	}
+	rings := *schedDecisionLog.rings.Load()
+	if int(pp.id) >= len(rings) {
+		return
+	}
+	var goid uint64
+	if gp != nil {
+		goid = gp.goid
+	}
+	r := &rings[pp.id]
+	n := r.n.Load()
+	r.buf[n%uint64(len(r.buf))] = schedDecision{when: nanotime(), goid: goid, pid: pp.id, source: src}
+	r.n.Store(n + 1)
+
+	// Ask for the schedule observers' goroutine to be woken if it
+	// waits for a decision, or if this ring is filling up. Idle
+	// decisions and decisions to run the goroutine itself don't wake
+	// it, or it would keep itself busy.
+	switch s := schedObservers.state.Load(); s {
+	case schedObserversParked:
+		if gp != nil && gp != schedObservers.g.ptr() {
+			schedObservers.state.CompareAndSwap(s, s|schedObserversWake)
+		}
+	case schedObserversSleeping:
+		if n+1-r.delivered.Load() >= uint64(len(r.buf))/16 {
+			schedObservers.state.CompareAndSwap(s, s|schedObserversWake)
+		}
+	}
+}
+
+// setSchedDecisionLog enables or disables the decision log for a test.
+// The log is not disabled while schedule observers are registered.
+func setSchedDecisionLog(enable bool) {
+	stw := stopTheWorldGC(stwSchedDecisionLog)
+	schedDecisionLog.forTest = enable
+	updateSchedDecisionLog()
+	startTheWorldGC(stw)
+}
+
+// updateSchedDecisionLog enables the decision log if a test or the
+// schedule observers use it, and disables it otherwise. Enabling it
+// discards previously logged decisions.
+//
+// The world must be stopped, so that no P logs a decision while the
+// rings are replaced, and the number of Ps can't change before the log
+// covers them all.
+func updateSchedDecisionLog() {
+	enable := schedDecisionLog.forTest || schedObservers.running
+	if enable && !schedDecisionLog.enabled.Load() {
+		rings := new([]schedDecisionRing)
+		*rings = make([]schedDecisionRing, len(allp))
+		schedDecisionLog.rings.Store(rings)
+	}
+	schedDecisionLog.enabled.Store(enable)
+}
+
+// growSchedDecisionLog adds rings for the Ps added by raising
+// GOMAXPROCS to nprocs, if the log is enabled. The world must be
+// stopped and sched.lock held.
+func growSchedDecisionLog(nprocs int32) {
+	assertLockHeld(&sched.lock)
+	rp := schedDecisionLog.rings.Load()
+	if !schedDecisionLog.enabled.Load() || rp == nil || int(nprocs) <= len(*rp) {
+		return
+	}
+	rings := new([]schedDecisionRing)
+	*rings = make([]schedDecisionRing, nprocs)
+	for i := range *rp {
+		old, r := &(*rp)[i], &(*rings)[i]
+		r.n.Store(old.n.Load())
+		r.delivered.Store(old.delivered.Load())
+		r.buf = old.buf
+	}
+	schedDecisionLog.rings.Store(rings)
+}
+
+// readSchedDecisionLog returns the logged decisions still held in the
+// rings, oldest first. The log should be disabled first, or entries
+// being written concurrently may be torn.
+func readSchedDecisionLog() []schedDecision {
+	rp := schedDecisionLog.rings.Load()
+	if rp == nil {
+		return nil
+	}
+	rings := *rp
+
+	// Each ring is in time order; merge them.
+	var lists [][]schedDecision
+	total := 0
+	for i := range rings {
+		r := &rings[i]
+		n := r.n.Load()
+		k := min(n, uint64(len(r.buf)))
+		l := make([]schedDecision, 0, k)
+		for j := n - k; j < n; j++ {
+			l = append(l, r.buf[j%uint64(len(r.buf))])
+		}
+		lists = append(lists, l)
+		total += len(l)
+	}
+	return mergeSchedDecisions(make([]schedDecision, 0, total), lists)
+}
+
+// mergeSchedDecisions appends the decisions of lists, each in time
+// order, to log in time order.
+func mergeSchedDecisions(log []schedDecision, lists [][]schedDecision) []schedDecision {
+	for {
+		first := -1
+		for i, l := range lists {
+			if len(l) > 0 && (first < 0 || l[0].when < lists[first][0].when) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return log
+		}
+		log = append(log, lists[first][0])
+		lists[first] = lists[first][1:]
+	}
+}
+
+// A ScheduleEvent describes a scheduling decision: where a P found
+// the goroutine it runs next, or that it found none.
+type ScheduleEvent struct {
+	// Source is where the goroutine was found: "local" for the P's
+	// own run queue, "global" for the global run queue, "steal" for
+	// another P's run queue, or "idle" if the P found nothing to run.
+	Source string
+
+	// Goid is the ID of the goroutine, or 0 if Source is "idle".
+	Goid uint64
+
+	// P is the ID of the P, from 0 to GOMAXPROCS-1.
+	P int
+
+	// Nanos is the time of the decision, in nanoseconds of the
+	// runtime's monotonic clock.
+	Nanos int64
+}
+
+// schedObserverPeriod is how long, in nanoseconds, the schedule
+// observers' goroutine lets decisions accumulate before it delivers
+// them.
+const schedObserverPeriod = 10e6
+
+// A schedObserver is a registered schedule observer.
+type schedObserver struct {
+	fn      func(ScheduleEvent)
+	since   int64       // nanotime when fn was registered
+	removed atomic.Bool // fn was unregistered
+}
+
+// States of the goroutine that delivers decisions to the schedule
+// observers, in schedObservers.state.
+const (
+	schedObserversRunning  = iota // running or runnable, or not started
+	schedObserversParked          // parked until a P logs a decision
+	schedObserversSleeping        // parked until the next delivery is due
+
+	// schedObserversWake is added to a parked state by a P that
+	// wants the goroutine woken. findRunnable wakes it.
+	schedObserversWake = 4
+)
+
+var schedObservers struct {
+	// list holds the registered observers. It is replaced, not
+	// modified, with the world stopped.
+	list atomic.Pointer[[]*schedObserver]
+
+	// running is set while the goroutine delivering decisions to
+	// the observers exists. It is changed with the world stopped.
+	running bool
+
+	// g is the goroutine delivering decisions, and timer wakes it
+	// when the next delivery is due. They are set by the goroutine.
+	g     guintptr
+	timer *timer
+
+	// state is one of the schedObserversRunning, ... constants.
+	// Waking the goroutine takes it from a parked state back to
+	// schedObserversRunning, so only one waker readies it.
+	state atomic.Uint32
+
+	// calling is set while the goroutine calls the observers. It
+	// counts as a user goroutine then. See isSystemGoroutine.
+	calling atomic.Bool
+
+	// dropped counts the decisions overwritten in the log before
+	// they could be delivered.
+	dropped atomic.Uint64
+}
+
+// RegisterScheduleObserver arranges for fn to be called with an event
+// for each scheduling decision made after it returns, until the
+// returned observer is unregistered.
+//
+// Observing the scheduler costs it little: decisions are buffered per P
+// and delivered in batches, up to 10ms later, by a single goroutine
+// that calls the observers in the order they were registered and the
+// events in time order. A P buffers a limited number of decisions, and
+// when the observers do not keep up, the oldest are dropped and counted
+// by the /sched/custom/observers/dropped:events metric, so observers
+// should return quickly.
+func RegisterScheduleObserver(fn func(ev ScheduleEvent)) ScheduleObserver {
+	o := &schedObserver{fn: fn, since: nanotime()}
+
+	stw := stopTheWorldGC(stwSchedDecisionLog)
+	l := new([]*schedObserver)
+	if old := schedObservers.list.Load(); old != nil {
+		*l = append(*l, *old...)
+	}
+	*l = append(*l, o)
+	schedObservers.list.Store(l)
+	start := !schedObservers.running
+	if start {
+		schedObservers.running = true
+		updateSchedDecisionLog()
+	}
+	startTheWorldGC(stw)
+
+	if start {
+		go runScheduleObservers()
+	}
+	return ScheduleObserver{o}
+}
+
+// ScheduleObserver is a handle to a schedule observer registered by
+// RegisterScheduleObserver.
+type ScheduleObserver struct {
+	o *schedObserver
+}
+
+// Unregister stops calls to the observer. Once Unregister returns, no
+// new calls begin, though a call that began earlier may still be
+// running. Unregistering an observer again has no effect.
+//
+// When the last observer is unregistered, the runtime stops the
+// goroutine that delivers events and stops logging decisions.
+func (h ScheduleObserver) Unregister() {
+	o := h.o
+	if o == nil || o.removed.Load() {
+		return
+	}
+	o.removed.Store(true)
+
+	stw := stopTheWorldGC(stwSchedDecisionLog)
+	l := new([]*schedObserver)
+	for _, x := range *schedObservers.list.Load() {
+		if x != o {
+			*l = append(*l, x)
+		}
+	}
+	last := len(*l) == 0
+	if last {
+		l = nil
+	}
+	schedObservers.list.Store(l)
+	startTheWorldGC(stw)
+
+	// Let the goroutine delivering decisions exit.
+	if last {
+		if gp := wakeScheduleObservers(); gp != nil {
+			goready(gp, 0)
+		}
+	}
+}
+
+// runScheduleObservers delivers the decisions logged by the Ps to the
+// schedule observers, until none are registered.
+func runScheduleObservers() {
+	schedObservers.g.set(getg())
+	t := new(timer)
+	t.init(func(any, uintptr, int64) {
+		if gp := wakeScheduleObservers(); gp != nil {
+			goready(gp, 0)
+		}
+	}, nil)
+	schedObservers.timer = t
+
+	var (
+		lists [][]schedDecision
+		log   []schedDecision
+	)
+	for {
+		// Wait for a decision, then for more to batch up with it.
+		gopark(parkScheduleObservers, nil, waitReasonScheduleObserverWait, traceBlockSystemGoroutine, 1)
+		gopark(sleepScheduleObservers, nil, waitReasonScheduleObserverWait, traceBlockSystemGoroutine, 1)
+		t.stop()
+
+		rings := *schedDecisionLog.rings.Load()
+		lists = lists[:0]
+		for i := range rings {
+			r := &rings[i]
+			size := uint64(len(r.buf))
+			n := r.n.Load()
+			first := r.delivered.Load()
+			if n-first > size {
+				schedObservers.dropped.Add(int64(n - first - size))
+				first = n - size
+			}
+			var l []schedDecision
+			for j := first; j < n; j++ {
+				l = append(l, r.buf[j%size])
+			}
+			// The owner of the P may have overwritten some
+			// decisions while they were copied, and may be
+			// overwriting one more.
+			if torn := int64(r.n.Load()+1-size) - int64(first); torn > 0 {
+				torn = min(torn, int64(len(l)))
+				schedObservers.dropped.Add(torn)
+				l = l[torn:]
+			}
+			r.delivered.Store(n)
+			lists = append(lists, l)
+		}
+		log = mergeSchedDecisions(log[:0], lists)
+
+		// Load the observers after collecting the decisions, so an
+		// observer registered since then has none of them.
+		if fns := schedObservers.list.Load(); fns != nil && len(log) > 0 {
+			schedObservers.calling.Store(true)
+			for _, d := range log {
+				ev := ScheduleEvent{Source: d.source.String(), Goid: d.goid, P: int(d.pid), Nanos: d.when}
+				for _, o := range *fns {
+					if d.when >= o.since && !o.removed.Load() {
+						o.fn(ev)
+					}
+				}
+			}
+			schedObservers.calling.Store(false)
+		}
+
+		if schedObservers.list.Load() == nil && stopScheduleObservers() {
+			return
+		}
+	}
+}
+
+// parkScheduleObservers is the gopark unlock function with which the
+// schedule observers' goroutine waits for a decision. It doesn't park
+// the goroutine if there are decisions to deliver already.
+func parkScheduleObservers(gp *g, _ unsafe.Pointer) bool {
+	schedObservers.state.Store(schedObserversParked)
+	// A P that logged a decision before the state changed did not
+	// ask for a wake-up. Take the state back unless one did since.
+	if schedDecisionsPending() && schedObservers.state.CompareAndSwap(schedObserversParked, schedObserversRunning) {
+		return false
+	}
+	return true
+}
+
+// sleepScheduleObservers is the gopark unlock function with which the
+// schedule observers' goroutine waits for the next delivery.
+func sleepScheduleObservers(gp *g, _ unsafe.Pointer) bool {
+	// Set the state first, so that the timer finds it.
+	schedObservers.state.Store(schedObserversSleeping)
+	schedObservers.timer.reset(nanotime()+schedObserverPeriod, 0)
+	return true
+}
+
+// wakeScheduleObservers takes over waking the schedule observers'
+// goroutine, if it is parked, and returns it. The caller must ready it.
+func wakeScheduleObservers() *g {
+	for {
+		s := schedObservers.state.Load()
+		if s == schedObserversRunning {
+			return nil
+		}
+		if schedObservers.state.CompareAndSwap(s, schedObserversRunning) {
+			return schedObservers.g.ptr()
+		}
+	}
+}
+
+// schedDecisionsPending reports whether the Ps logged decisions that
+// were not delivered to the schedule observers yet.
+func schedDecisionsPending() bool {
+	rings := *schedDecisionLog.rings.Load()
+	for i := range rings {
+		if rings[i].n.Load() != rings[i].delivered.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// stopScheduleObservers ends the schedule observers' goroutine, which
+// calls it, if no observers are registered, and reports whether it did.
+func stopScheduleObservers() bool {
+	stw := stopTheWorldGC(stwSchedDecisionLog)
+	stop := schedObservers.list.Load() == nil
+	if stop {
+		schedObservers.running = false
+		schedObservers.g = 0
+		updateSchedDecisionLog()
+	}
+	startTheWorldGC(stw)
+	return stop
+}
@@ -0,0 +1,29 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !schedstats
+
+package runtime
+
+const schedStatsEnabled = false
+
+type gSchedStats struct{}
+
+func (s *gSchedStats) recordRun(id int32) {}
+
+func (s *gSchedStats) countSwitch(involuntary bool) {}
+
+func (s *gSchedStats) countAsyncPreempt() {}
+
+func (s *gSchedStats) contextSwitches() (nvcsw, nivcsw uint64) {
+	return 0, 0
+}
+
+func (s *gSchedStats) forcedPreemptions() uint64 {
+	return 0
+}
+
+func (s *gSchedStats) runs() pHistory {
+	return pHistory{}
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build schedstats
+
+package runtime
+
+const schedStatsEnabled = true
+
+// gSchedStats is the per-G scheduling statistics. This is embedded in
+// the g struct.
+type gSchedStats struct {
+	// runHistory records the Ps this G most recently ran on.
+	runHistory pHistory
+
+	// asyncPreempts counts the asynchronous preemptions of this G.
+	asyncPreempts uint32
+
+	// nvcsw and nivcsw count the times this G was descheduled
+	// voluntarily and involuntarily, like nvcsw and nivcsw in p.
+	nvcsw  uint32
+	nivcsw uint32
+}
+
+// recordRun notes that the G is about to run on P id.
+func (s *gSchedStats) recordRun(id int32) {
+	s.runHistory.record(id)
+}
+
+// countSwitch counts a voluntary or involuntary deschedule of the G.
+func (s *gSchedStats) countSwitch(involuntary bool) {
+	if involuntary {
+		s.nivcsw++
+	} else {
+		s.nvcsw++
+	}
+}
+
+// countAsyncPreempt counts an asynchronous preemption of the G.
+func (s *gSchedStats) countAsyncPreempt() {
+	s.asyncPreempts++
+}
+
+func (s *gSchedStats) contextSwitches() (nvcsw, nivcsw uint64) {
+	return uint64(s.nvcsw), uint64(s.nivcsw)
+}
+
+func (s *gSchedStats) forcedPreemptions() uint64 {
+	return uint64(s.asyncPreempts)
+}
+
+func (s *gSchedStats) runs() pHistory {
+	return s.runHistory
+}
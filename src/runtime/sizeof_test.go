@@ -15,14 +15,15 @@ import (
 
 func TestSizeof(t *testing.T) {
 	const _64bit = unsafe.Sizeof(uintptr(0)) == 8
-	const xreg = unsafe.Sizeof(runtime.XRegPerG{}) // Varies per architecture
+	const xreg = unsafe.Sizeof(runtime.XRegPerG{})     // Varies per architecture
+	const stats = unsafe.Sizeof(runtime.GSchedStats{}) // Empty unless built with -tags schedstats
 	var tests = []struct {
 		val    any     // type as a value
 		_32bit uintptr // size on 32bit platforms
 		_64bit uintptr // size on 64bit platforms
 	}{
-		{runtime.G{}, 280 + xreg, 440 + xreg}, // g, but exported for testing
-		{runtime.Sudog{}, 64, 104},            // sudog, but exported for testing
+		{runtime.G{}, 280 + xreg + stats, 440 + xreg + stats}, // g, but exported for testing
+		{runtime.Sudog{}, 64, 104},                            // sudog, but exported for testing
 	}
 
 	if xreg > runtime.PtrSize {
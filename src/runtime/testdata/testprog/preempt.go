@@ -12,6 +12,7 @@ import (
 
 func init() {
 	register("AsyncPreempt", AsyncPreempt)
+	register("AsyncPreemptEnabled", AsyncPreemptEnabled)
 }
 
 func AsyncPreempt() {
@@ -73,3 +74,7 @@ var out int64
 
 //go:noinline
 func dummy() {}
+
+func AsyncPreemptEnabled() {
+	println(runtime.AsyncPreemptEnabled())
+}
@@ -0,0 +1,234 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Schedule observers can't be removed from a test binary once a test
+// leaks one, and while any is registered the decision log can't be
+// disabled, so they are tested here, each in its own process.
+
+func init() {
+	register("ScheduleObserver", ScheduleObserver)
+	register("ScheduleObserverDrops", ScheduleObserverDrops)
+	register("ScheduleObserverUnregister", ScheduleObserverUnregister)
+	register("ScheduleObserverDeadlock", ScheduleObserverDeadlock)
+}
+
+const droppedMetric = "/sched/custom/observers/dropped:events"
+
+func droppedScheduleEvents() uint64 {
+	s := []metrics.Sample{{Name: droppedMetric}}
+	metrics.Read(s)
+	return s[0].Value.Uint64()
+}
+
+// curGoid returns the ID of the calling goroutine.
+func curGoid() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	b = b[:bytes.IndexByte(b, ' ')]
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// pingPong hands control back and forth between the calling goroutine
+// and a new one, rounds times, and returns the new goroutine's ID.
+func pingPong(rounds int) uint64 {
+	ping := make(chan bool)
+	pong := make(chan uint64)
+	go func() {
+		id := curGoid()
+		for range ping {
+			pong <- id
+		}
+	}()
+	var id uint64
+	for range rounds {
+		ping <- true
+		id = <-pong
+	}
+	close(ping)
+	return id
+}
+
+// waitFor calls cond until it returns true, and exits if that takes
+// too long.
+func waitFor(what string, cond func() bool) {
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			fmt.Printf("timed out waiting for %s\n", what)
+			os.Exit(1)
+		}
+	}
+}
+
+func checkScheduleEvent(ev runtime.ScheduleEvent) error {
+	switch ev.Source {
+	case "local", "global", "steal":
+		if ev.Goid == 0 {
+			return fmt.Errorf("event from %s has no goroutine: %+v", ev.Source, ev)
+		}
+	case "idle":
+		if ev.Goid != 0 {
+			return fmt.Errorf("idle event has a goroutine: %+v", ev)
+		}
+	default:
+		return fmt.Errorf("event has invalid source: %+v", ev)
+	}
+	if ev.P < 0 || ev.P >= runtime.GOMAXPROCS(0) {
+		return fmt.Errorf("event has invalid P: %+v", ev)
+	}
+	return nil
+}
+
+func ScheduleObserver() {
+	// With one P, every round blocks both goroutines.
+	runtime.GOMAXPROCS(1)
+
+	var (
+		mu     sync.Mutex
+		err    error
+		counts = make(map[uint64]int)
+	)
+	obs := runtime.RegisterScheduleObserver(func(ev runtime.ScheduleEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil {
+			err = checkScheduleEvent(ev)
+		}
+		counts[ev.Goid]++
+	})
+	defer obs.Unregister()
+	dropped := droppedScheduleEvents()
+
+	// Each round runs the other goroutine, and then this one. The
+	// rounds fit in the Ps' buffers, so none of them can be dropped.
+	const rounds = 100
+	me := curGoid()
+	other := pingPong(rounds)
+	waitFor("ping-pong events", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[me] >= rounds && counts[other] >= rounds
+	})
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if n := droppedScheduleEvents() - dropped; n != 0 {
+		fmt.Printf("%d events dropped\n", n)
+		return
+	}
+	fmt.Println("OK")
+}
+
+func ScheduleObserverDrops() {
+	// Block delivery while the scheduler is busy, so that the Ps
+	// overwrite decisions that were not delivered yet.
+	var once sync.Once
+	blocked := make(chan bool)
+	unblock := make(chan bool)
+	obs := runtime.RegisterScheduleObserver(func(runtime.ScheduleEvent) {
+		once.Do(func() {
+			close(blocked)
+			<-unblock
+		})
+	})
+	defer obs.Unregister()
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				runtime.Gosched()
+			}
+		}()
+	}
+	<-blocked
+	dropped := droppedScheduleEvents()
+	time.Sleep(100 * time.Millisecond)
+	close(unblock)
+
+	waitFor("dropped events", func() bool {
+		return droppedScheduleEvents() > dropped
+	})
+	stop.Store(true)
+	wg.Wait()
+	fmt.Println("OK")
+}
+
+func ScheduleObserverUnregister() {
+	var a, b atomic.Int64
+	obsA := runtime.RegisterScheduleObserver(func(runtime.ScheduleEvent) {
+		a.Add(1)
+	})
+	obsB := runtime.RegisterScheduleObserver(func(runtime.ScheduleEvent) {
+		b.Add(1)
+	})
+	moreEvents := func(what string) {
+		n := b.Load()
+		pingPong(100)
+		waitFor(what, func() bool {
+			return b.Load() > n
+		})
+	}
+
+	moreEvents("events")
+	if a.Load() == 0 {
+		fmt.Println("first observer got no events")
+		return
+	}
+	obsA.Unregister()
+	obsA.Unregister()
+
+	// B still observes the scheduler, and A doesn't. A call to A
+	// that began before A was unregistered is over once B is called
+	// for a later event.
+	moreEvents("events after unregistering")
+	n := a.Load()
+	moreEvents("more events after unregistering")
+	if a.Load() != n {
+		fmt.Printf("unregistered observer called %d times\n", a.Load()-n)
+		return
+	}
+	obsB.Unregister()
+
+	// Observers can be registered again.
+	var c atomic.Int64
+	obsC := runtime.RegisterScheduleObserver(func(runtime.ScheduleEvent) {
+		c.Add(1)
+	})
+	pingPong(100)
+	waitFor("events after registering again", func() bool {
+		return c.Load() > 0
+	})
+	obsC.Unregister()
+	fmt.Println("OK")
+}
+
+func ScheduleObserverDeadlock() {
+	runtime.RegisterScheduleObserver(func(runtime.ScheduleEvent) {})
+	pingPong(100)
+	select {}
+}
@@ -1417,6 +1417,16 @@ func isSystemGoroutine(gp *g, fixed bool) bool {
 		}
 		return !gp.runningCleanups.Load()
 	}
+	if gp.startpc == abi.FuncPCABIInternal(runScheduleObservers) {
+		// We include the schedule observers' goroutine if it's
+		// calling back into user code.
+		if fixed {
+			// This goroutine can vary. In fixed mode,
+			// always consider it a user goroutine.
+			return false
+		}
+		return !schedObservers.calling.Load()
+	}
 	return stringslite.HasPrefix(funcname(f), "runtime.")
 }
 